@@ -0,0 +1,49 @@
+package b24
+
+import "image/color"
+
+// DefaultCLUT is the common fixed color map defined in ARIB STD-B24, shared by captions and broadcaster logos
+var DefaultCLUT = buildDefaultCLUT()
+
+func buildDefaultCLUT() color.Palette {
+	levels := []uint8{0, 85, 170, 255}
+	opaque := []color.RGBA{
+		{0, 0, 0, 255}, {255, 0, 0, 255}, {0, 255, 0, 255}, {255, 255, 0, 255},
+		{0, 0, 255, 255}, {255, 0, 255, 255}, {0, 255, 255, 255}, {255, 255, 255, 255},
+		{0, 0, 0, 0},
+		{170, 0, 0, 255}, {0, 170, 0, 255}, {170, 170, 0, 255}, {0, 0, 170, 255},
+		{170, 0, 170, 255}, {0, 170, 170, 255}, {170, 170, 170, 255},
+	}
+	// index 16-64: remaining combinations of the 4 levels in R-G-B order, excluding those already listed above
+	for _, r := range levels {
+		for _, g := range levels {
+			for _, b := range levels {
+				c := color.RGBA{r, g, b, 255}
+				if !containsColor(opaque, c) {
+					opaque = append(opaque, c)
+				}
+			}
+		}
+	}
+	clut := make(color.Palette, 0, 128)
+	for _, c := range opaque {
+		clut = append(clut, c)
+	}
+	// index 65-127: half transparent version of index 0-63 except transparent one
+	for i, c := range opaque[:64] {
+		if i == 8 {
+			continue
+		}
+		clut = append(clut, color.RGBA{c.R, c.G, c.B, 128})
+	}
+	return clut
+}
+
+func containsColor(colors []color.RGBA, c color.RGBA) bool {
+	for _, existing := range colors {
+		if existing == c {
+			return true
+		}
+	}
+	return false
+}
@@ -33,8 +33,11 @@ const (
 	b24CSKanji         b24GCharset = 0x42
 	b24CSAlphabet      b24GCharset = 0x4a
 	b24CSX0201Katakana b24GCharset = 0x49
-	b24CSDRCS0         b24GCharset = 0xfe // not supported, 2 bytes
-	b24CSDRCS          b24GCharset = 0xff // not supported, 1 byte
+	b24CSMacro         b24GCharset = 0x70 // 1 byte
+	b24CSDRCS1         b24GCharset = 0xc1 // DRCS-1 to DRCS-15 are 0xc1-0xcf, 1 byte
+	b24CSDRCS15        b24GCharset = 0xcf
+	b24CSDRCS0         b24GCharset = 0xfe // 2 bytes
+	b24CSDRCS          b24GCharset = 0xff // unknown DRCS set, 1 byte
 )
 
 var (
@@ -53,24 +56,29 @@ var (
 )
 
 type decodeState struct {
-	G0          b24GCharset
-	G1          b24GCharset
-	G2          b24GCharset
-	G3          b24GCharset
-	GL          b24GCharset
-	GR          b24GCharset
-	GLSSVictim  b24GCharset
+	G           [4]b24GCharset
+	GL          int
+	GR          int
+	GLSSVictim  int
 	SJISDecoder *encoding.Decoder
+	Gaiji       func(b0, b1 uint8) string
+	DRCS        func(code uint16) string
 }
 
-func newDecodeState() *decodeState {
-	return &decodeState{b24CSKanji, b24CSAlphabet, b24CSHiragana, b24CSKatakana, b24CSKanji, b24CSHiragana, b24CSNone, japanese.ShiftJIS.NewDecoder()}
+func newDecodeState(initial InitialState) *decodeState {
+	return &decodeState{initial.G, initial.GL, initial.GR, initial.GL, japanese.ShiftJIS.NewDecoder(), func(uint8, uint8) string { return "" }, func(uint16) string { return "" }}
 }
 
 func getCharset1Byte(bytes []byte) (b24GCharset, int, error) {
 	switch bytes[0] {
 	case 0x20:
 		// 1-byte DRCS
+		if len(bytes) > 1 && bytes[1] == uint8(b24CSMacro) {
+			return b24CSMacro, 1, nil
+		}
+		if len(bytes) > 1 && 0x41 <= bytes[1] && bytes[1] <= 0x4f {
+			return b24CSDRCS1 + b24GCharset(bytes[1]-0x41), 1, nil
+		}
 		return b24CSDRCS, 1, nil
 	case uint8(b24CSProAlphabet):
 		fallthrough
@@ -115,13 +123,33 @@ func getCharset2Bytes(bytes []byte, onlyCheckDRCS bool) (b24GCharset, int, error
 			additionalSeqLen = 1
 			return b24CSDRCS0, additionalSeqLen, nil
 		} else {
-			return b24CSNone, 0, errors.New(fmt.Sprintf("not supported drcs0 g0 sequence: %02x", bytes[0:2]))
+			return b24CSNone, 0, errors.New(fmt.Sprintf("not supported drcs0 g0 sequence: %02x", head(bytes, 2)))
 		}
 	default:
-		return b24CSNone, 0, errors.New(fmt.Sprintf("not supported 2-byte encoding switch sequence: %02x", bytes[0:2]))
+		return b24CSNone, 0, errors.New(fmt.Sprintf("not supported 2-byte encoding switch sequence: %02x", head(bytes, 2)))
 	}
 }
 
+// escSeqLen gives the length of the escape sequence at the head of bytes, i.e. ESC, intermediate bytes and the final byte.
+// false is returned if the final byte is missing.
+func escSeqLen(bytes []byte) (int, bool) {
+	n := 1
+	for n < len(bytes) && 0x20 <= bytes[n] && bytes[n] <= 0x2F {
+		n++
+	}
+	if n < len(bytes) {
+		return n + 1, true
+	}
+	return n, false
+}
+
+func head(bytes []byte, n int) []byte {
+	if len(bytes) < n {
+		return bytes
+	}
+	return bytes[:n]
+}
+
 func (s *decodeState) changeState(bytes []byte) (int, bool, error) {
 	seqLen := 0
 	ss := false
@@ -130,6 +158,9 @@ func (s *decodeState) changeState(bytes []byte) (int, bool, error) {
 		if len(bytes) == 1 { // ignore
 			return 0, false, nil
 		}
+		if _, ok := escSeqLen(bytes); !ok {
+			return 0, false, errors.New(fmt.Sprintf("truncated esc seq: %02x", bytes))
+		}
 		seqLen = 1
 		switch bytes[1] {
 		case 0x24:
@@ -142,116 +173,104 @@ func (s *decodeState) changeState(bytes []byte) (int, bool, error) {
 					return 0, false, err
 				}
 				seqLen += additionalSeqLen + 1
-				s.G0 = bcs
+				s.G[0] = bcs
 			case 0x29:
 				bcs, additionalSeqLen, err := getCharset2Bytes(bytes[3:], false)
 				if err != nil {
 					return 0, false, err
 				}
 				seqLen += additionalSeqLen + 1
-				s.G1 = bcs
+				s.G[1] = bcs
 			case 0x2A:
 				bcs, additionalSeqLen, err := getCharset2Bytes(bytes[3:], false)
 				if err != nil {
 					return 0, false, err
 				}
 				seqLen += additionalSeqLen + 1
-				s.G2 = bcs
+				s.G[2] = bcs
 			case 0x2B:
 				bcs, additionalSeqLen, err := getCharset2Bytes(bytes[3:], false)
 				if err != nil {
 					return 0, false, err
 				}
 				seqLen += additionalSeqLen + 1
-				s.G3 = bcs
+				s.G[3] = bcs
 			default:
 				bcs, additionalSeqLen, err := getCharset2Bytes(bytes[2:], false)
 				if err != nil {
 					return 0, false, err
 				}
 				if bcs == b24CSDRCS0 {
-					return 0, false, errors.New(fmt.Sprintf("unexpected g0 drcs0 seq: %02x", bytes[0:4]))
+					return 0, false, errors.New(fmt.Sprintf("unexpected g0 drcs0 seq: %02x", head(bytes, 4)))
 				}
 				seqLen += additionalSeqLen
-				s.G0 = bcs
+				s.G[0] = bcs
 			}
 		case 0x28:
 			// G0 1-byte set
-			if len(bytes) == 1 { // ignore
-				return 0, false, nil
-			}
 			bcs, additionalSeqLen, err := getCharset1Byte(bytes[2:])
 			if err != nil {
 				return 0, false, err
 			}
 			seqLen += additionalSeqLen + 1
-			s.G0 = bcs
+			s.G[0] = bcs
 		case 0x29:
 			// G1 1-byte set
-			if len(bytes) == 1 { // ignore
-				return 0, false, nil
-			}
 			bcs, additionalSeqLen, err := getCharset1Byte(bytes[2:])
 			if err != nil {
 				return 0, false, err
 			}
 			seqLen += additionalSeqLen + 1
-			s.G1 = bcs
+			s.G[1] = bcs
 		case 0x2A:
 			// G2 1-byte set
-			if len(bytes) == 1 { // ignore
-				return 0, false, nil
-			}
 			bcs, additionalSeqLen, err := getCharset1Byte(bytes[2:])
 			if err != nil {
 				return 0, false, err
 			}
 			seqLen += additionalSeqLen + 1
-			s.G2 = bcs
+			s.G[2] = bcs
 		case 0x2B:
 			// G3 1-byte set
-			if len(bytes) == 1 { // ignore
-				return 0, false, nil
-			}
 			bcs, additionalSeqLen, err := getCharset1Byte(bytes[2:])
 			if err != nil {
 				return 0, false, err
 			}
 			seqLen += additionalSeqLen + 1
-			s.G3 = bcs
+			s.G[3] = bcs
 		case 0x6E:
 			// LS2
-			s.GL = s.G2
+			s.GL = 2
 		case 0x6F:
 			// LS3
-			s.GL = s.G3
+			s.GL = 3
 		case 0x7E:
 			// LS1R
-			s.GR = s.G1
+			s.GR = 1
 		case 0x7D:
 			// LS2R
-			s.GR = s.G2
+			s.GR = 2
 		case 0x7C:
 			// LS3R
-			s.GR = s.G3
+			s.GR = 3
 		default:
 			return 0, false, errors.New(fmt.Sprintf("unknown esc seq: %02x", bytes[0:2]))
 		}
 	case 0x0F:
 		// LS0
-		s.GL = s.G0
+		s.GL = 0
 	case 0x0E:
 		// LS1
-		s.GL = s.G1
+		s.GL = 1
 	case 0x19:
 		// SS2
 		s.GLSSVictim = s.GL
-		s.GL = s.G2
+		s.GL = 2
 		ss = true
 	case 0x1D:
 		// SS3
 		s.GLSSVictim = s.GL
-		s.GL = s.G3
+		s.GL = 3
 		ss = true
 	default:
 		// ignore
@@ -260,11 +279,11 @@ func (s *decodeState) changeState(bytes []byte) (int, bool, error) {
 }
 
 func (s *decodeState) decodeGL(bytes []byte, repeatTime int) (string, int, error) {
-	return s.decodeByGCharset(bytes, s.GL, repeatTime)
+	return s.decodeByGCharset(bytes, s.G[s.GL], repeatTime)
 }
 
 func (s *decodeState) decodeGR(bytes []byte, repeatTime int) (string, int, error) {
-	return s.decodeByGCharset(bytes, s.GR, repeatTime)
+	return s.decodeByGCharset(bytes, s.G[s.GR], repeatTime)
 }
 
 func (s *decodeState) decodeByGCharset(bytes []byte, gset b24GCharset, repeatTime int) (string, int, error) {
@@ -286,88 +305,228 @@ func (s *decodeState) decodeByGCharset(bytes []byte, gset b24GCharset, repeatTim
 	case b24CSX0201Katakana:
 		resultString, err = s.SJISDecoder.String(string([]byte{bytes[0] + 0x20}))
 	case b24CSKanji:
+		// JIS X 0208 is a subset of JIS X 0213 plane 1
 		fallthrough
 	case b24CSX0213Plane1:
-		sjisBytes := make([]byte, 2)
-		if b0 <= 62 {
-			sjisBytes[0] = 0x80 + ((b0 + 1) >> 1)
-		} else {
-			sjisBytes[0] = 0xC0 + ((b0 + 1) >> 1)
-		}
-		if b0&1 == 1 {
-			if b1 <= 63 {
-				sjisBytes[1] = 0x3F + b1
-			} else {
-				sjisBytes[1] = 0x40 + b1
-			}
-		} else {
-			sjisBytes[1] = 0x9E + b1
-		}
-		resultString, err = s.SJISDecoder.String(string(sjisBytes))
+		resultString, err = decodeJISX0213(1, b0, b1)
 		seqLen = 1
 	case b24CSX0213Plane2:
-		sjisBytes := make([]byte, 2)
-		sjisBytes[0] = 0xEF + ((b0 + 1) >> 1)
-		if b0&1 == 1 {
-			if b1 <= 63 {
-				sjisBytes[1] = 0x3F + b1
-			} else {
-				sjisBytes[1] = 0x40 + b1
-			}
-		} else {
-			sjisBytes[1] = 0x9E + b1
-		}
-		resultString, err = s.SJISDecoder.String(string(sjisBytes))
+		resultString, err = decodeJISX0213(2, b0, b1)
 		seqLen = 1
 	case b24CSGaiji:
-		resultString = tryGaiji(b0, b1)
+		resultString = s.Gaiji(b0, b1)
 		seqLen = 1
 	case b24CSIgnore1Byte:
 		fallthrough
+	case b24CSMacro:
+		fallthrough
 	case b24CSDRCS:
 		return "", 0, nil
 	case b24CSDRCS0:
-		return "", 1, nil
+		resultString = s.DRCS(uint16(b0+0x20)<<8 | uint16(b1+0x20))
+		seqLen = 1
 	default:
+		if b24CSDRCS1 <= gset && gset <= b24CSDRCS15 {
+			resultString = s.DRCS(uint16(gset-b24CSDRCS1+0x41)<<8 | uint16(b0+0x20))
+			break
+		}
 		err = errors.New("unreachable in decodedByGSet")
 	}
 	return strings.Repeat(resultString, repeatTime), seqLen, err
 }
 
+func decodeJISX0213(plane, row, cell uint8) (string, error) {
+	if row < 1 || row > 94 || cell < 1 || cell > 94 {
+		return "", errors.New(fmt.Sprintf("illegal jis x 0213 code point: %d-%d-%d", plane, row, cell))
+	}
+	if combining, ok := jisX0213Combining[jisX0213Point{plane, row, cell}]; ok {
+		return combining, nil
+	}
+	table := &jisX0213Plane1
+	if plane == 2 {
+		table = &jisX0213Plane2
+	}
+	r := table[(int(row)-1)*94+int(cell)-1]
+	if r == 0 {
+		return "", errors.New(fmt.Sprintf("unassigned jis x 0213 code point: %d-%d-%d", plane, row, cell))
+	}
+	return string(r), nil
+}
+
+// DecodeString decodes SI text, e.g. in EIT and SDT, with the initial state of SIInitialState
 func DecodeString(bytes []byte) (string, error) {
-	state := newDecodeState()
-	decoded := ""
-	ss := false
-	ssCount := 0
-	inMarcoDef := false
-	repeatTime := 1
-	repeated := false
+	return NewDecoder(SIInitialState).Decode(bytes)
+}
 
-	for i := 0; i < len(bytes); i++ {
-		if ss {
-			ssCount++
-		}
-		if ssCount > 1 {
-			ss = false
-			state.GL = state.GLSSVictim
-			ssCount = 0
-		}
-		if repeatTime > 1 {
-			if repeated {
-				repeatTime = 1
-			}
-			repeated = !repeated
+// DecodeStringLenient decodes SI text like DecodeString, but replaces undecodable bytes with U+FFFD instead of failing
+func DecodeStringLenient(bytes []byte) (string, []Warning) {
+	decoder := NewDecoder(SIInitialState)
+	decoder.Lenient = true
+	decoded, _ := decoder.Decode(bytes)
+	return decoded, decoder.Warnings()
+}
+
+// Warning records what is replaced by the placeholder in lenient mode
+type Warning struct {
+	Offset  int    `json:"offset"` // byte offset in the input of Decode
+	Message string `json:"message"`
+}
+
+func (w Warning) String() string {
+	return fmt.Sprintf("offset %d: %s", w.Offset, w.Message)
+}
+
+// Decoder keeps designation and invocation state across calls of Decode,
+// as needed by caption data units and macro-based text
+type Decoder struct {
+	// Lenient makes Decode output Placeholder and keep going on undecodable bytes, instead of returning an error
+	Lenient bool
+	// Placeholder replaces undecodable bytes in lenient mode, U+FFFD if empty.
+	// It also replaces unassigned gaiji in text and Unicode mode, see GaijiMode.
+	Placeholder string
+	// GaijiMode selects how the additional symbols are rendered
+	GaijiMode GaijiMode
+	// GaijiTable overrides the rendering of the listed gaiji, in any mode
+	GaijiTable GaijiTable
+	// CollectMarks keeps program marks like [字] out of the text, and collects them to be given by Marks
+	CollectMarks bool
+	// DRCSTable replaces DRCS characters loaded by LoadDRCS, by the hashes of their patterns
+	DRCSTable DRCSTable
+	// NormalizeWidth folds the widths of the output by FoldWidth, whatever the character size is
+	NormalizeWidth bool
+
+	initial    InitialState
+	state      *decodeState
+	ss         bool
+	inMarcoDef bool
+	repeatTime int
+	warnings   []Warning
+	marks      Mark
+	style      Style
+	palette    uint8
+	format     DisplayFormat
+	runs       []Run
+	macros     map[byte][]byte
+	drcs       map[uint16]DRCSFont
+	macroDepth int
+	macroCode  int // code of the macro being defined, -1 until it is read
+	macroDef   []byte
+	macroExec  bool
+}
+
+func NewDecoder(initial InitialState) *Decoder {
+	d := &Decoder{initial: initial}
+	d.Reset()
+	return d
+}
+
+// Reset restores the initial designation and invocation state. User-defined macros and loaded DRCS are kept.
+func (d *Decoder) Reset() {
+	d.state = newDecodeState(d.initial)
+	d.state.Gaiji = d.renderGaiji
+	d.state.DRCS = d.renderDRCS
+	d.ss = false
+	d.inMarcoDef = false
+	d.repeatTime = 1
+	d.warnings = nil
+	d.marks = 0
+	d.style = DefaultStyle
+	d.palette = 0
+	d.format = DefaultDisplayFormat
+}
+
+// Size returns the current character size
+func (d *Decoder) Size() CharacterSize {
+	return d.style.Size
+}
+
+// sized renders decoded characters by the current character size
+func (d *Decoder) sized(str string) string {
+	if d.style.Size == SizeMiddle {
+		// full-width alphanumerics in middle size are shown half-width
+		return halfWidthAlphanumeric(str)
+	}
+	return str
+}
+
+// Warnings returns the warnings collected in lenient mode since the last Reset
+func (d *Decoder) Warnings() []Warning {
+	return d.warnings
+}
+
+// recover records the error as a warning and gives the placeholder in lenient mode, or returns the error as is
+func (d *Decoder) recover(offset int, err error) (string, error) {
+	if !d.Lenient {
+		return "", err
+	}
+	d.warnings = append(d.warnings, Warning{offset, err.Error()})
+	if d.Placeholder == "" {
+		return "\uFFFD", nil
+	}
+	return d.Placeholder, nil
+}
+
+func (d *Decoder) endCharacter() {
+	if d.ss {
+		d.ss = false
+		d.state.GL = d.state.GLSSVictim
+	}
+	d.repeatTime = 1
+}
+
+// Decode decodes bytes into plain text, with APR and CS given as "\n" and "\f"
+func (d *Decoder) Decode(bytes []byte) (string, error) {
+	runs, err := d.DecodeStyled(bytes)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	for _, run := range runs {
+		sb.WriteString(run.Text)
+	}
+	return sb.String(), nil
+}
+
+// DecodeStyled decodes bytes into text runs with the style and the active position changes, e.g. for captions
+func (d *Decoder) DecodeStyled(bytes []byte) ([]Run, error) {
+	d.runs = nil
+	if err := d.decode(bytes); err != nil {
+		return nil, err
+	}
+	runs := d.runs
+	d.runs = nil
+	if d.NormalizeWidth {
+		for i := range runs {
+			runs[i].Text = FoldWidth(runs[i].Text)
 		}
+	}
+	return runs, nil
+}
+
+func (d *Decoder) decode(bytes []byte) error {
+	state := d.state
+
+	for i := 0; i < len(bytes); i++ {
 		b := bytes[i]
 		b1 := uint8(0)
 		if i+1 < len(bytes) {
 			b1 = bytes[i+1]
 		}
-		if inMarcoDef {
-			if b == 0x95 && b1 == 0x4f {
+		if d.inMarcoDef {
+			if d.macroCode < 0 {
+				d.macroCode = int(b & 0x7f)
+			} else if b == 0x95 && b1 == 0x4f {
 				// MARCO DEF END
 				i++
-				inMarcoDef = false
+				d.inMarcoDef = false
+				d.DefineMacro(byte(d.macroCode), d.macroDef)
+				if d.macroExec {
+					if err := d.invokeMacro(byte(d.macroCode)); err != nil {
+						return err
+					}
+				}
+			} else {
+				d.macroDef = append(d.macroDef, b)
 			}
 			continue
 		}
@@ -376,55 +535,109 @@ func DecodeString(bytes []byte) (string, error) {
 			switch b {
 			case 0x0c:
 				// CS
-				decoded += "\f"
+				d.emit("\f")
 			case 0x0d:
 				// APR
-				decoded += "\n"
+				d.emit("\n")
 			case 0x16:
 				// PAPF, takes one 1-byte parameter
 				i++
+				d.advance(int(b1 & 0x3f))
 			case 0x1c:
 				// APS, takes two 1-byte parameters
+				if i+2 < len(bytes) {
+					d.moveTo(Position{int(b1 & 0x3f), int(bytes[i+2] & 0x3f)})
+				}
 				i += 2
 			case 0x20:
 				// SP
-				decoded += " "
+				d.emit(strings.Repeat(" ", d.repeatTime))
+				d.endCharacter()
 			default:
-				seqLen, ss1, err := state.changeState(bytes[i:])
+				seqLen, ss, err := state.changeState(bytes[i:])
 				if err != nil {
-					return "", err
+					placeholder, err := d.recover(i, err)
+					if err != nil {
+						return err
+					}
+					d.emit(placeholder)
+					if b == 0x1B {
+						seqLen, _ = escSeqLen(bytes[i:])
+						seqLen--
+					}
+				}
+				if ss {
+					d.ss = true
 				}
-				ss = ss1
 				i += seqLen
 			}
+		} else if 0x20 < b && b < 0x80 && state.G[state.GL] == b24CSMacro {
+			d.endCharacter()
+			if err := d.invokeMacro(b); err != nil {
+				return err
+			}
+		} else if 0xa0 < b && state.G[state.GR] == b24CSMacro {
+			d.endCharacter()
+			if err := d.invokeMacro(b); err != nil {
+				return err
+			}
 		} else if 0x20 < b && b < 0x80 {
-			str, additionalLen, err := state.decodeGL(bytes[i:], repeatTime)
+			str, additionalLen, err := state.decodeGL(bytes[i:], d.repeatTime)
 			if err != nil {
-				return "", err
+				str, err = d.recover(i, err)
+				if err != nil {
+					return err
+				}
 			}
 			i += additionalLen
-			decoded += str
+			d.emit(d.sized(str))
+			d.endCharacter()
 		} else if 0x80 <= b && b < 0xA0 {
-			// C1 Set, ignored
+			// C1 Set
 			switch b {
+			case 0x80, 0x81, 0x82, 0x83, 0x84, 0x85, 0x86, 0x87:
+				// BKF, RDF, GRF, YLF, BLF, MGF, CNF, WHF
+				d.style.Foreground = d.palette<<4 | b&0x07
+			case 0x88:
+				// SSZ
+				d.style.Size = SizeSmall
+			case 0x89:
+				// MSZ
+				d.style.Size = SizeMiddle
+			case 0x8A:
+				// NSZ
+				d.style.Size = SizeNormal
 			case 0x8B:
-				// SZX
-				fallthrough
+				// SZX, takes one 1-byte parameter
+				i++
+				if size, ok := sizeOfSZX(b1); ok {
+					d.style.Size = size
+				}
 			case 0x90:
-				// COL
-				fallthrough
+				// COL, takes one 1-byte parameter, or two with the palette
+				i++
+				if b1 == 0x20 {
+					if i+1 < len(bytes) {
+						d.palette = bytes[i+1] & 0x07
+					}
+					i++
+				} else {
+					d.setColor(b1)
+				}
 			case 0x91:
-				// FLC
-				fallthrough
+				// FLC, takes one 1-byte parameter
+				i++
+				d.style.Flashing = flashingOf(b1)
 			case 0x93:
-				// POL
-				fallthrough
-			case 0x94:
-				// WMM
-				fallthrough
+				// POL, takes one 1-byte parameter
+				i++
+				d.style.Polarity = Polarity(b1 & 0x03)
 			case 0x97:
-				// HLC
-				// takes one 1-byte parameter
+				// HLC, takes one 1-byte parameter
+				i++
+				d.style.Highlight = Highlight(b1 & 0x0f)
+			case 0x94:
+				// WMM, takes one 1-byte parameter
 				i++
 			case 0x9D:
 				// TIME
@@ -439,34 +652,61 @@ func DecodeString(bytes []byte) (string, error) {
 					i++
 				}
 			case 0x95:
-				// MARCO
-				if b1 == 0x40 {
-					inMarcoDef = true
+				// MARCO, 0x40 starts a definition, and 0x41 starts a definition to be executed after it
+				if b1 == 0x40 || b1 == 0x41 {
+					i++
+					d.inMarcoDef = true
+					d.macroCode = -1
+					d.macroDef = nil
+					d.macroExec = b1 == 0x41
 				}
+			case 0x9B:
+				// CSI, takes parameters up to the intermediate byte and the final byte
+				seqLen, err := d.applyCSI(bytes[i:])
+				if err != nil {
+					placeholder, err := d.recover(i, err)
+					if err != nil {
+						return err
+					}
+					d.emit(placeholder)
+				}
+				i += seqLen - 1
 			case 0x98:
-				// RPC
-				repeatTime = int(b1 & 0x3f)
-				if repeatTime == 0 {
+				// RPC, repeats the next character
+				i++
+				d.repeatTime = int(b1 & 0x3f)
+				if d.repeatTime == 0 {
 					// expected NL w/o CR effect by spec, though NLCR is applied
-					repeatTime = 1
-					decoded += "\n"
+					d.repeatTime = 1
+					d.emit("\n")
 				}
 			}
 		} else if 0xa0 < b {
-			str, additionalLen, err := state.decodeGR(bytes[i:], repeatTime)
+			str, additionalLen, err := state.decodeGR(bytes[i:], d.repeatTime)
 			if err != nil {
-				return "", err
+				str, err = d.recover(i, err)
+				if err != nil {
+					return err
+				}
 			}
 			i += additionalLen
-			decoded += str
+			d.emit(d.sized(str))
+			d.endCharacter()
 		}
 	}
-	return decoded, nil
+	return nil
 }
 
-func tryGaiji(b0, b1 uint8) string {
-	if 90 <= b0 && b0 <= 94 {
-		return _gaijiSet[b0-90][b1]
+// lookupGaiji gives the character of the additional kanji or symbol, or false if it is unassigned
+func lookupGaiji(b0, b1 uint8) (string, bool) {
+	var row []string
+	if 85 <= b0 && b0 <= 86 {
+		row = _additionalKanjiSet[b0-85]
+	} else if 90 <= b0 && b0 <= 94 {
+		row = _gaijiSet[b0-90]
+	}
+	if int(b1) < len(row) && row[b1] != "" {
+		return row[b1], true
 	}
-	return fmt.Sprintf("{gaiji %02x%02x}", b0+0x20, b1+0x20)
+	return "", false
 }
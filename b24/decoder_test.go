@@ -20,3 +20,60 @@ func TestDecodeString(t *testing.T) {
 		t.Fatalf("result is not what expected: %s", decoded)
 	}
 }
+
+func TestDecoderKeepsState(t *testing.T) {
+	decoder := NewDecoder(SIInitialState)
+	// ESC ( J designates alphabet to G0, which is invoked to GL
+	decoded, err := decoder.Decode([]byte{0x1b, 0x28, 0x4a, 0x41})
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded2, err := decoder.Decode([]byte{0x42, 0x98, 0x43, 0xa2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded+decoded2 != "ABあああ" {
+		t.Fatalf("result is not what expected: %s%s", decoded, decoded2)
+	}
+	decoder.Reset()
+	decoded, err = decoder.Decode([]byte{0x30, 0x21})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "亜" {
+		t.Fatalf("result is not what expected after reset: %s", decoded)
+	}
+}
+
+func TestDecoderLenient(t *testing.T) {
+	testVector := []byte{0xa2, 0x1b, 0x24, 0x50, 0xa4, 0x1b, 0x7b, 0xa6}
+	if _, err := DecodeString(testVector); err == nil {
+		t.Fatal("strict decoding should fail")
+	}
+	decoded, warnings := DecodeStringLenient(testVector)
+	if decoded != "あ�い�う" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+	if len(warnings) != 2 || warnings[0].Offset != 1 || warnings[1].Offset != 5 {
+		t.Fatalf("unexpected warnings: %v", warnings)
+	}
+	decoder := NewDecoder(SIInitialState)
+	decoder.Lenient = true
+	decoder.Placeholder = "?"
+	decoded, err := decoder.Decode(testVector)
+	if err != nil || decoded != "あ?い?う" {
+		t.Fatalf("result is not what expected: %s, %v", decoded, err)
+	}
+}
+
+func TestDecodeJISX0213(t *testing.T) {
+	// plane 1 addition 1-14-1, combining sequence 1-4-87, then plane 2 2-1-1
+	testVector := []byte{0x1b, 0x24, 0x39, 0x2e, 0x21, 0x24, 0x77, 0x1b, 0x24, 0x3a, 0x21, 0x21}
+	decoded, err := DecodeString(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "俱か゚𠂉" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
@@ -0,0 +1,131 @@
+package b24
+
+import (
+	"errors"
+	"fmt"
+)
+
+// DisplayFormat is the layout of the display area set by the CSI sequences SWF, SDF, SDP, SSM, SHS and SVS, in dots
+type DisplayFormat struct {
+	// Format is the parameter of SWF, e.g. 7 for 960x540 horizontal
+	Format int
+	// Width and Height are the size of the display area set by SDF
+	Width  int
+	Height int
+	// X and Y are the upper left of the display area set by SDP
+	X int
+	Y int
+	// CharacterWidth and CharacterHeight are the character size set by SSM
+	CharacterWidth  int
+	CharacterHeight int
+	// HorizontalSpacing and VerticalSpacing are the spacing between characters and between lines set by SHS and SVS
+	HorizontalSpacing int
+	VerticalSpacing   int
+}
+
+// DefaultDisplayFormat is the default of TR-B14 captions, 960x540 horizontal with 36 dot characters
+var DefaultDisplayFormat = DisplayFormat{
+	Format: 7, Width: 960, Height: 540,
+	CharacterWidth: 36, CharacterHeight: 36, HorizontalSpacing: 4, VerticalSpacing: 24,
+}
+
+// swfArea gives the display area of the horizontal formats of SWF
+var swfArea = map[int][2]int{5: {1920, 1080}, 7: {960, 540}, 9: {720, 480}}
+
+// Plane gives the size of the whole plane of the format of SWF, which the display area is placed in
+func (f DisplayFormat) Plane() (int, int) {
+	if area, ok := swfArea[f.Format]; ok {
+		return area[0], area[1]
+	}
+	return 960, 540
+}
+
+// Dot gives the lower left of the character at the active position, where the spacing is split on both sides.
+// Middle size takes half of the width, as in the active position moves of MSZ.
+func (f DisplayFormat) Dot(position Position, size CharacterSize) (int, int) {
+	cellWidth := f.CharacterWidth + f.HorizontalSpacing
+	if size == SizeMiddle {
+		cellWidth /= 2
+	}
+	cellHeight := f.CharacterHeight + f.VerticalSpacing
+	x := f.X + position.Column*cellWidth + f.HorizontalSpacing/2
+	y := f.Y + (position.Row+1)*cellHeight - f.VerticalSpacing/2
+	return x, y
+}
+
+// Format returns the display format set by the CSI sequences
+func (d *Decoder) Format() DisplayFormat {
+	return d.format
+}
+
+// csiLen gives the length of the CSI sequence at the head of bytes, i.e. CSI, parameters, the intermediate byte 0x20 and the final byte.
+// false is returned if the final byte is missing.
+func csiLen(bytes []byte) (int, bool) {
+	n := 1
+	for n < len(bytes) && bytes[n] != 0x20 {
+		n++
+	}
+	if n+1 < len(bytes) && 0x40 <= bytes[n+1] && bytes[n+1] <= 0x6F {
+		return n + 2, true
+	}
+	return len(bytes), false
+}
+
+// csiParameters parses the parameters separated by ';'
+func csiParameters(bytes []byte) ([]int, error) {
+	params := []int{0}
+	for _, b := range bytes {
+		switch {
+		case b == 0x3B:
+			params = append(params, 0)
+		case 0x30 <= b && b <= 0x39:
+			params[len(params)-1] = params[len(params)-1]*10 + int(b-0x30)
+		default:
+			return nil, errors.New(fmt.Sprintf("illegal csi parameter: %02x", bytes))
+		}
+	}
+	return params, nil
+}
+
+// applyCSI keeps the display format by the CSI sequence at the head of bytes. The other sequences are skipped.
+func (d *Decoder) applyCSI(bytes []byte) (int, error) {
+	n, ok := csiLen(bytes)
+	if !ok {
+		return n, errors.New(fmt.Sprintf("truncated csi seq: %02x", bytes))
+	}
+	params, err := csiParameters(bytes[1 : n-2])
+	if err != nil {
+		return n, err
+	}
+	switch bytes[n-1] {
+	case 0x53:
+		// SWF, the other display settings take the defaults of the format
+		d.format = DefaultDisplayFormat
+		d.format.Format = params[0]
+		if area, ok := swfArea[params[0]]; ok {
+			d.format.Width, d.format.Height = area[0], area[1]
+		}
+	case 0x56:
+		// SDF
+		if len(params) >= 2 {
+			d.format.Width, d.format.Height = params[0], params[1]
+		}
+	case 0x5F:
+		// SDP
+		if len(params) >= 2 {
+			d.format.X, d.format.Y = params[0], params[1]
+		}
+	case 0x57:
+		// SSM
+		if len(params) >= 2 {
+			d.format.CharacterWidth, d.format.CharacterHeight = params[0], params[1]
+		}
+	case 0x58:
+		// SHS
+		d.format.HorizontalSpacing = params[0]
+	case 0x59:
+		// SVS
+		d.format.VerticalSpacing = params[0]
+	}
+	return n, nil
+}
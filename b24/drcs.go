@@ -0,0 +1,217 @@
+package b24
+
+import (
+	"bufio"
+	"crypto/md5"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strings"
+)
+
+// DRCSFont is a pattern of a DRCS character
+type DRCSFont struct {
+	FontID uint8
+	Mode   uint8
+	// Depth is the number of gradation levels minus 2
+	Depth   uint8
+	Width   uint8
+	Height  uint8
+	Pattern []byte
+	// Geometric is the geometric data in the modes other than 0 and 1, where Pattern is empty
+	Geometric []byte
+}
+
+// DRCSCharacter is a character defined in a DRCS data unit.
+// Code is e.g. 0x4121 for 0x21 of DRCS-1, or the 2 bytes of DRCS-0.
+type DRCSCharacter struct {
+	Code  uint16
+	Fonts []DRCSFont
+}
+
+// IsPattern reports whether the font is given by a bitmap pattern
+func (f DRCSFont) IsPattern() bool {
+	return f.Mode <= 1
+}
+
+// BitsPerPixel gives the bits of a pixel in the pattern
+func (f DRCSFont) BitsPerPixel() int {
+	if f.Mode == 0 {
+		return 1
+	}
+	bits := 1
+	for 1<<bits < int(f.Depth)+2 {
+		bits++
+	}
+	return bits
+}
+
+// Image gives the pattern as a gray image, with the highest gradation level as white, or nil for geometric fonts
+func (f DRCSFont) Image() *image.Gray {
+	if !f.IsPattern() {
+		return nil
+	}
+	img := image.NewGray(image.Rect(0, 0, int(f.Width), int(f.Height)))
+	bits := f.BitsPerPixel()
+	maxLevel := 1<<bits - 1
+	if f.Mode == 1 {
+		maxLevel = int(f.Depth) + 1
+	}
+	for y := 0; y < int(f.Height); y++ {
+		for x := 0; x < int(f.Width); x++ {
+			offset := (y*int(f.Width) + x) * bits
+			level := 0
+			for i := 0; i < bits; i++ {
+				bit := offset + i
+				if bit/8 >= len(f.Pattern) {
+					break
+				}
+				level = level<<1 | int(f.Pattern[bit/8]>>(7-bit%8)&1)
+			}
+			if level > maxLevel {
+				level = maxLevel
+			}
+			img.Pix[y*img.Stride+x] = uint8(level * 255 / maxLevel)
+		}
+	}
+	return img
+}
+
+// Hash gives the MD5 of the pattern in upper case hex, which DRCS tables are keyed by
+func (f DRCSFont) Hash() string {
+	sum := md5.Sum(f.Pattern)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// ParseDRCS parses the data of a DRCS data unit, i.e. data unit parameter 0x30 for 1-byte DRCS or 0x31 for 2-byte DRCS
+func ParseDRCS(data []byte) ([]DRCSCharacter, error) {
+	if len(data) < 1 {
+		return nil, errors.New("drcs data too short")
+	}
+	numberOfCode := int(data[0])
+	pos := 1
+	characters := make([]DRCSCharacter, 0, numberOfCode)
+	for i := 0; i < numberOfCode; i++ {
+		if pos+3 > len(data) {
+			return nil, errors.New("drcs data truncated at character code")
+		}
+		character := DRCSCharacter{Code: binary.BigEndian.Uint16(data[pos:])}
+		numberOfFont := int(data[pos+2])
+		pos += 3
+		for j := 0; j < numberOfFont; j++ {
+			if pos+1 > len(data) {
+				return nil, errors.New("drcs data truncated at font")
+			}
+			font := DRCSFont{FontID: data[pos] >> 4, Mode: data[pos] & 0x0f}
+			pos++
+			if font.IsPattern() {
+				if pos+3 > len(data) {
+					return nil, errors.New("drcs data truncated at pattern header")
+				}
+				font.Depth, font.Width, font.Height = data[pos], data[pos+1], data[pos+2]
+				pos += 3
+				size := (int(font.Width)*int(font.Height)*font.BitsPerPixel() + 7) / 8
+				if pos+size > len(data) {
+					return nil, errors.New(fmt.Sprintf("drcs pattern of %04x truncated", character.Code))
+				}
+				font.Pattern = data[pos : pos+size]
+				pos += size
+			} else {
+				if pos+4 > len(data) {
+					return nil, errors.New("drcs data truncated at geometric header")
+				}
+				length := int(binary.BigEndian.Uint16(data[pos+2:]))
+				pos += 4
+				if pos+length > len(data) {
+					return nil, errors.New(fmt.Sprintf("drcs geometric data of %04x truncated", character.Code))
+				}
+				font.Geometric = data[pos : pos+length]
+				pos += length
+			}
+			character.Fonts = append(character.Fonts, font)
+		}
+		characters = append(characters, character)
+	}
+	return characters, nil
+}
+
+// DRCSTable maps the hashes of DRCS patterns to their replacement text
+type DRCSTable map[string]string
+
+// LoadDRCSTable reads a DRCS table of lines like "<MD5 hash>=<replacement>", or separated by whitespace.
+// Lines starting with #, ; or [ are skipped, so that ini files of existing caption tools can be read.
+func LoadDRCSTable(r io.Reader) (DRCSTable, error) {
+	table := DRCSTable{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.ContainsAny(line[:1], "#;[") {
+			continue
+		}
+		hash, replacement := line, ""
+		if i := strings.IndexAny(line, "= \t"); i >= 0 {
+			hash, replacement = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		if _, err := hex.DecodeString(hash); err != nil || len(hash) != 32 {
+			return nil, errors.New(fmt.Sprintf("drcs table line %d: illegal hash %q", lineNo, hash))
+		}
+		table[strings.ToUpper(hash)] = replacement
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// LoadDRCSTableFile reads a DRCS table file in the format of LoadDRCSTable
+func LoadDRCSTableFile(path string) (DRCSTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadDRCSTable(f)
+}
+
+// LoadDRCS parses a DRCS data unit and keeps the characters for the following text
+func (d *Decoder) LoadDRCS(data []byte) error {
+	characters, err := ParseDRCS(data)
+	if err != nil {
+		return err
+	}
+	if d.drcs == nil {
+		d.drcs = map[uint16]DRCSFont{}
+	}
+	for _, character := range characters {
+		if len(character.Fonts) > 0 {
+			d.drcs[character.Code] = character.Fonts[0]
+		}
+	}
+	return nil
+}
+
+// DRCS gives the font loaded for the code
+func (d *Decoder) DRCS(code uint16) (DRCSFont, bool) {
+	font, ok := d.drcs[code]
+	return font, ok
+}
+
+// renderDRCS gives the replacement of the DRCS character by DRCSTable.
+// Characters not loaded are dropped, and ones loaded but not in the table are shown with their hash.
+func (d *Decoder) renderDRCS(code uint16) string {
+	font, ok := d.drcs[code]
+	if !ok {
+		return ""
+	}
+	hash := font.Hash()
+	if text, ok := d.DRCSTable[hash]; ok {
+		return text
+	}
+	return fmt.Sprintf("{drcs %s}", hash)
+}
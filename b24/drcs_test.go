@@ -0,0 +1,56 @@
+package b24
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDRCS(t *testing.T) {
+	// 1 code 0x4121 with 1 font, 4 levels, 4x2 pixels
+	data := []byte{0x01, 0x41, 0x21, 0x01, 0x01, 0x02, 0x04, 0x02, 0x1b, 0xf0}
+	characters, err := ParseDRCS(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(characters) != 1 || characters[0].Code != 0x4121 || len(characters[0].Fonts) != 1 {
+		t.Fatalf("unexpected characters: %+v", characters)
+	}
+	img := characters[0].Fonts[0].Image()
+	expected := []uint8{0, 85, 170, 255, 255, 255, 0, 0}
+	for i, v := range expected {
+		if img.Pix[i/4*img.Stride+i%4] != v {
+			t.Fatalf("pixel %d is %d, not %d", i, img.Pix[i/4*img.Stride+i%4], v)
+		}
+	}
+	if _, err := ParseDRCS(data[:8]); err == nil {
+		t.Fatal("truncated data should fail")
+	}
+
+	decoder := NewDecoder(CaptionInitialState)
+	if err := decoder.LoadDRCS(data); err != nil {
+		t.Fatal(err)
+	}
+	// designates DRCS-1 to G1, invokes it to GL
+	text := []byte{0xa2, 0x1b, 0x29, 0x20, 0x41, 0x0e, 0x21}
+	hash := characters[0].Fonts[0].Hash()
+	decoded, err := decoder.Decode(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "あ{drcs "+hash+"}" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+
+	decoder.Reset()
+	decoder.DRCSTable, err = LoadDRCSTable(strings.NewReader("[DRCS_Map]\n" + strings.ToLower(hash) + "=★\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err = decoder.Decode(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "あ★" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
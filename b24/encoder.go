@@ -0,0 +1,288 @@
+package b24
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// encodeCandidate is a way to encode a token, the code in a graphic set
+type encodeCandidate struct {
+	set  b24GCharset
+	code []byte // GL code, 0x21-0x7E
+}
+
+var (
+	encodeTokens      map[string][]encodeCandidate
+	encodeMaxTokenLen int
+	encodeTokensOnce  sync.Once
+)
+
+// addEncodeToken registers a candidate of the token, keeping the first code found for each set
+func addEncodeToken(token string, set b24GCharset, code ...byte) {
+	if token == "" {
+		return
+	}
+	for _, c := range encodeTokens[token] {
+		if c.set == set {
+			return
+		}
+	}
+	encodeTokens[token] = append(encodeTokens[token], encodeCandidate{set, code})
+	if n := len([]rune(token)); n > encodeMaxTokenLen {
+		encodeMaxTokenLen = n
+	}
+}
+
+// buildEncodeTokens builds the reverse tables of what the decoder gives for each set
+func buildEncodeTokens() {
+	encodeTokens = map[string][]encodeCandidate{}
+	for b := byte(0x21); b < 0x7F; b++ {
+		addEncodeToken(string([]byte{b}), b24CSAlphabet, b)
+		addEncodeToken(b24HiraganaSet[b-0x20], b24CSHiragana, b)
+		addEncodeToken(b24KatakanaSet[b-0x20], b24CSKatakana, b)
+	}
+	for row := 1; row <= 94; row++ {
+		for cell := 1; cell <= 94; cell++ {
+			code := []byte{byte(row + 0x20), byte(cell + 0x20)}
+			if r := jisX0213Plane1[(row-1)*94+cell-1]; r != 0 {
+				// JIS X 0208 characters go to the kanji set, the others to JIS X 0213 plane 1
+				if isJISX0208(row, cell) {
+					addEncodeToken(string(r), b24CSKanji, code...)
+				} else {
+					addEncodeToken(string(r), b24CSX0213Plane1, code...)
+				}
+			}
+			if r := jisX0213Plane2[(row-1)*94+cell-1]; r != 0 {
+				addEncodeToken(string(r), b24CSX0213Plane2, code...)
+			}
+		}
+	}
+	for point, combining := range jisX0213Combining {
+		set := b24CSX0213Plane1
+		if point.plane == 2 {
+			set = b24CSX0213Plane2
+		}
+		addEncodeToken(combining, set, point.row+0x20, point.cell+0x20)
+	}
+	for i, row := range _additionalKanjiSet {
+		for cell, s := range row {
+			addEncodeToken(s, b24CSGaiji, byte(85+i+0x20), byte(cell+0x20))
+		}
+	}
+	for i, row := range _gaijiSet {
+		for cell, s := range row {
+			addEncodeToken(s, b24CSGaiji, byte(90+i+0x20), byte(cell+0x20))
+		}
+	}
+}
+
+// jisX0208Cells are the ranges of cells of JIS X 0208 in the rows partly assigned, as the other cells of the kanji set
+// are not rendered by receivers. NEC and IBM extensions like row 13 are not included.
+var jisX0208Cells = map[int][][2]int{
+	2:  {{1, 14}, {26, 33}, {42, 48}, {60, 74}, {82, 89}, {94, 94}},
+	3:  {{16, 25}, {33, 58}, {65, 90}},
+	4:  {{1, 83}},
+	5:  {{1, 86}},
+	6:  {{1, 24}, {33, 56}},
+	7:  {{1, 33}, {49, 81}},
+	8:  {{1, 32}},
+	47: {{1, 51}},
+	84: {{1, 6}},
+}
+
+// isJISX0208 reports whether the code point of JIS X 0213 plane 1 is in JIS X 0208, i.e. the kanji set,
+// which is rows 1-8 and 16-84 with the unassigned cells left out
+func isJISX0208(row, cell int) bool {
+	if cells, ok := jisX0208Cells[row]; ok {
+		for _, r := range cells {
+			if r[0] <= cell && cell <= r[1] {
+				return true
+			}
+		}
+		return false
+	}
+	return row == 1 || 16 <= row && row <= 83
+}
+
+// designation gives the escape sequence designating the set to G[i]
+func designation(set b24GCharset, i int) []byte {
+	switch set {
+	case b24CSKanji, b24CSX0213Plane1, b24CSX0213Plane2, b24CSGaiji:
+		if i == 0 {
+			return []byte{0x1B, 0x24, byte(set)}
+		}
+		return []byte{0x1B, 0x24, byte(0x28 + i), byte(set)}
+	}
+	return []byte{0x1B, byte(0x28 + i), byte(set)}
+}
+
+var (
+	lockingShiftGL = [][]byte{{0x0F}, {0x0E}, {0x1B, 0x6E}, {0x1B, 0x6F}}
+	lockingShiftGR = [][]byte{nil, {0x1B, 0x7E}, {0x1B, 0x7D}, {0x1B, 0x7C}}
+	singleShift    = [][]byte{nil, nil, {0x19}, {0x1D}}
+)
+
+type encodeState struct {
+	G  [4]b24GCharset
+	GL int
+	GR int
+}
+
+// encodeNode is the cheapest way found to reach a state at a position
+type encodeNode struct {
+	state encodeState
+	cost  int
+	prev  *encodeNode
+	bytes [3][]byte // designation, invocation and code, to save allocations
+}
+
+// encodeStep holds the nodes reached at a position, in the order found so that ties are broken deterministically
+type encodeStep struct {
+	nodes []*encodeNode
+	index map[encodeState]int
+}
+
+func (s *encodeStep) offer(node *encodeNode) {
+	if s.index == nil {
+		s.index = map[encodeState]int{}
+	}
+	if i, ok := s.index[node.state]; ok {
+		if node.cost < s.nodes[i].cost {
+			s.nodes[i] = node
+		}
+		return
+	}
+	s.index[node.state] = len(s.nodes)
+	s.nodes = append(s.nodes, node)
+}
+
+// maxShiftCost bounds the bytes needed to turn a state into any other: designating G0-G3 and invoking GL and GR
+const maxShiftCost = 3 + 4 + 4 + 4 + 2 + 2
+
+// prune drops the nodes which cannot be better than the cheapest one, as that can reach their state within maxShiftCost
+func (s *encodeStep) prune() {
+	min := s.nodes[0].cost
+	for _, node := range s.nodes {
+		if node.cost < min {
+			min = node.cost
+		}
+	}
+	nodes := s.nodes[:0]
+	for _, node := range s.nodes {
+		if node.cost <= min+maxShiftCost {
+			nodes = append(nodes, node)
+		}
+	}
+	s.nodes = nodes
+}
+
+// offerCandidate offers every way to output the candidate from the node
+func offerCandidate(next *encodeStep, node *encodeNode, c encodeCandidate) {
+	gr := make([]byte, len(c.code))
+	for i, b := range c.code {
+		gr[i] = b | 0x80
+	}
+	for i := 0; i < 4; i++ {
+		state := node.state
+		var prefix []byte
+		if state.G[i] != c.set {
+			prefix = designation(c.set, i)
+			state.G[i] = c.set
+		}
+		offer := func(state encodeState, shift, code []byte) {
+			cost := node.cost + len(prefix) + len(shift) + len(code)
+			if i, ok := next.index[state]; ok && next.nodes[i].cost <= cost {
+				return
+			}
+			next.offer(&encodeNode{state, cost, node, [3][]byte{prefix, shift, code}})
+		}
+		if state.GL == i {
+			offer(state, nil, c.code)
+		}
+		if state.GR == i {
+			offer(state, nil, gr)
+		}
+		if singleShift[i] != nil {
+			offer(state, singleShift[i], c.code)
+		}
+		if state.GL != i {
+			locked := state
+			locked.GL = i
+			offer(locked, lockingShiftGL[i], c.code)
+		}
+		if state.GR != i && lockingShiftGR[i] != nil {
+			locked := state
+			locked.GR = i
+			offer(locked, lockingShiftGR[i], gr)
+		}
+	}
+}
+
+// EncodeString encodes s into ARIB STD-B24 8-unit code, from the initial state of SIInitialState.
+// The shortest sequence of designations and invocations is chosen, and DecodeString gives s back.
+func EncodeString(s string) ([]byte, error) {
+	return encode(s, SIInitialState)
+}
+
+func encode(s string, initial InitialState) ([]byte, error) {
+	encodeTokensOnce.Do(buildEncodeTokens)
+	runes := []rune(s)
+	unencodable := -1
+	steps := make([]encodeStep, len(runes)+1)
+	steps[0].offer(&encodeNode{state: encodeState{initial.G, initial.GL, initial.GR}})
+	for pos := 0; pos < len(runes); pos++ {
+		if len(steps[pos].nodes) == 0 {
+			continue
+		}
+		steps[pos].prune()
+		switch runes[pos] {
+		case ' ':
+			// SP, whatever the state is
+			for _, node := range steps[pos].nodes {
+				steps[pos+1].offer(&encodeNode{node.state, node.cost + 1, node, [3][]byte{nil, nil, {0x20}}})
+			}
+			continue
+		case '\n':
+			// APR
+			for _, node := range steps[pos].nodes {
+				steps[pos+1].offer(&encodeNode{node.state, node.cost + 1, node, [3][]byte{nil, nil, {0x0D}}})
+			}
+			continue
+		}
+		found := false
+		for n := 1; n <= encodeMaxTokenLen && pos+n <= len(runes); n++ {
+			candidates, ok := encodeTokens[string(runes[pos:pos+n])]
+			if !ok {
+				continue
+			}
+			found = true
+			for _, node := range steps[pos].nodes {
+				for _, c := range candidates {
+					offerCandidate(&steps[pos+n], node, c)
+				}
+			}
+		}
+		if !found && unencodable < 0 {
+			unencodable = pos
+		}
+	}
+	var best *encodeNode
+	for _, node := range steps[len(runes)].nodes {
+		if best == nil || node.cost < best.cost {
+			best = node
+		}
+	}
+	if best == nil {
+		return nil, errors.New(fmt.Sprintf("cannot encode %q at %d", runes[unencodable], unencodable))
+	}
+	var parts [][]byte
+	for node := best; node.prev != nil; node = node.prev {
+		parts = append(parts, node.bytes[2], node.bytes[1], node.bytes[0])
+	}
+	var result []byte
+	for i := len(parts) - 1; i >= 0; i-- {
+		result = append(result, parts[i]...)
+	}
+	return result, nil
+}
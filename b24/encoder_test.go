@@ -0,0 +1,77 @@
+package b24
+
+import (
+	"bytes"
+	"math/rand"
+	"sort"
+	"testing"
+)
+
+func TestEncodeString(t *testing.T) {
+	encoded, err := EncodeString("あいう")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, []byte{0xa2, 0xa4, 0xa6}) {
+		t.Fatalf("result is not the shortest: %02x", encoded)
+	}
+	// JIS X 0208 characters are in the kanji set, and the NEC extensions are not
+	encoded, err = EncodeString("〜")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, []byte{0x21, 0x41}) {
+		t.Fatalf("〜 is not encoded in the kanji set: %02x", encoded)
+	}
+	encoded, err = EncodeString("①")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(encoded, []byte{0x1b, 0x24, 0x39, 0x2d, 0x21}) {
+		t.Fatalf("① is not encoded in JIS X 0213 plane 1: %02x", encoded)
+	}
+	if _, err := EncodeString("OK😀"); err == nil {
+		t.Fatal("unencodable character should fail")
+	}
+	for _, s := range []string{
+		"[新]仮面ライダーリバイス　第1話「家族!契約!悪魔ささやく!」[デ][字]",
+		"ＮＨＫ総合１・東京",
+		"か゚𠀋𩸽 (1)⑳",
+		"Line 1\nLine 2",
+	} {
+		assertRoundTrip(t, s)
+	}
+}
+
+func TestEncodeStringRoundTrip(t *testing.T) {
+	encodeTokensOnce.Do(buildEncodeTokens)
+	var tokens []string
+	for token := range encodeTokens {
+		tokens = append(tokens, token)
+	}
+	// in a fixed order for the seeded strings to be the same on every run
+	sort.Strings(tokens)
+	rng := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		s := ""
+		for n := rng.Intn(10); n >= 0; n-- {
+			s += tokens[rng.Intn(len(tokens))]
+		}
+		assertRoundTrip(t, s)
+	}
+}
+
+func assertRoundTrip(t *testing.T, s string) {
+	t.Helper()
+	encoded, err := EncodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := DecodeString(encoded)
+	if err != nil {
+		t.Fatalf("%q: %v", s, err)
+	}
+	if decoded != s {
+		t.Fatalf("round trip of %q gives %q", s, decoded)
+	}
+}
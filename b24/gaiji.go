@@ -1,5 +1,24 @@
 package b24
 
+// _additionalKanjiSet is row 85-86 of the gaiji set, additional kanji mapped to Unicode by ARIB STD-B62
+var _additionalKanjiSet = [][]string{
+	[]string{
+		"", "㐂", "𠅘", "份", "仿", "侚", "俉", "傜", "儞", "冼", "㔟", "匇", "卡", "卬", "詹", "𠮷",
+		"呍", "咖", "咜", "咩", "唎", "啊", "噲", "囤", "圳", "圴", "塚", "墀", "姤", "娣", "婕", "寬",
+		"﨑", "㟢", "庬", "弴", "彅", "德", "怗", "恵", "愰", "昤", "曈", "曙", "曺", "曻", "桒", "鿄",
+		"椑", "椻", "橅", "檑", "櫛", "𣏌", "𣏾", "𣗄", "毱", "泠", "洮", "海", "涿", "淊", "淸", "渚",
+		"潞", "濹", "灤", "𤋮", "煇", "燁", "爀", "玟", "玨", "珉", "珖", "琛", "琡", "琢", "琦", "琪",
+		"琬", "琹", "瑋", "㻚", "畵", "疁", "睲", "䂓", "磈", "磠", "祇", "禮", "鿆", "䄃", "鿅",
+	},
+	[]string{
+		"", "秚", "稞", "筿", "簱", "䉤", "綋", "羡", "脘", "脺", "舘", "芮", "葛", "蓜", "蓬", "蕙",
+		"藎", "蝕", "蟬", "蠋", "裵", "角", "諶", "跎", "辻", "迶", "郝", "鄧", "鄭", "醲", "鈳", "銈",
+		"錡", "鍈", "閒", "雞", "餃", "饀", "髙", "鯖", "𩸽", "鷗", "麴", "麵",
+	},
+}
+
+// _gaijiSet is row 90-94 of the gaiji set, additional symbols.
+// Road signs and the other symbols having no Unicode character in ARIB STD-B62 are left empty, to be rendered as unassigned.
 var _gaijiSet = [][]string{
 	[]string{
 		"", "⛌", "⛍", "❗", "⛏", "⛐", "⛑", "", "⛒", "⛕", "⛓", "⛔", "", "", "", "",
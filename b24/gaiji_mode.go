@@ -0,0 +1,251 @@
+package b24
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// GaijiMode selects how the additional symbols in row 90-94 of the gaiji set are rendered.
+// No private use mode is given, as the code points of the symbols in the private use area differ by font;
+// load the mapping of the font by LoadGaijiTable instead.
+type GaijiMode uint8
+
+const (
+	// GaijiText renders program marks as bracketed text like [字], and the other symbols as Unicode.
+	// Unassigned gaiji are shown as Placeholder of the decoder, or as {gaiji xxxx} with the code if it is empty.
+	GaijiText GaijiMode = iota
+	// GaijiUnicode renders every symbol as its Unicode character given by ARIB STD-B62, e.g. 🈑 for [字].
+	// Unassigned gaiji are shown as Placeholder of the decoder, or as U+FFFD if it is empty.
+	GaijiUnicode
+	// GaijiASCII renders symbols as ASCII only text like [Sub], dropping those having no ASCII form and unassigned gaiji
+	GaijiASCII
+	// GaijiRemove drops the symbols and unassigned gaiji
+	GaijiRemove
+)
+
+func (m GaijiMode) String() string {
+	switch m {
+	case GaijiText:
+		return "text"
+	case GaijiUnicode:
+		return "unicode"
+	case GaijiASCII:
+		return "ascii"
+	case GaijiRemove:
+		return "remove"
+	}
+	return fmt.Sprintf("GaijiMode(%d)", uint8(m))
+}
+
+// ParseGaijiMode parses the name given by GaijiMode.String
+func ParseGaijiMode(name string) (GaijiMode, error) {
+	for m := GaijiText; m <= GaijiRemove; m++ {
+		if m.String() == name {
+			return m, nil
+		}
+	}
+	return 0, errors.New("unknown gaiji mode: " + name)
+}
+
+// GaijiTable maps gaiji codes, e.g. 0x7A56 for [字], to their replacement text
+type GaijiTable map[uint16]string
+
+// LoadGaijiTable reads a gaiji table of lines like "7A56 [字]",
+// with the hex code and the replacement separated by whitespace.
+// A line with no replacement removes the symbol, and lines starting with # are comments.
+func LoadGaijiTable(r io.Reader) (GaijiTable, error) {
+	table := GaijiTable{}
+	scanner := bufio.NewScanner(r)
+	lineNo := 0
+	for scanner.Scan() {
+		lineNo++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		codeStr, replacement := line, ""
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			codeStr, replacement = line[:i], strings.TrimSpace(line[i+1:])
+		}
+		code, err := strconv.ParseUint(codeStr, 16, 16)
+		if err != nil {
+			return nil, errors.New(fmt.Sprintf("gaiji table line %d: illegal code %q", lineNo, codeStr))
+		}
+		table[uint16(code)] = replacement
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return table, nil
+}
+
+// LoadGaijiTableFile reads a gaiji table file in the format of LoadGaijiTable
+func LoadGaijiTableFile(path string) (GaijiTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return LoadGaijiTable(f)
+}
+
+// Mark is a set of program marks, row 90 cell 48-82 of the gaiji set except ■ and ●
+type Mark uint64
+
+const (
+	MarkHV Mark = 1 << iota
+	MarkSD
+	MarkProgressive
+	MarkWide
+	MarkMultiView
+	MarkSignLanguage
+	MarkCaption
+	MarkInteractive
+	MarkData
+	MarkStereo
+	MarkBilingual
+	MarkMultiAudio
+	MarkCommentary
+	MarkSurround
+	MarkB
+	MarkN
+)
+
+const (
+	MarkWeather Mark = 1 << (iota + 18)
+	MarkTraffic
+	MarkMovie
+	MarkFree
+	MarkPay
+	MarkAgeLimit
+	MarkFirstPart
+	MarkLastPart
+	MarkRerun
+	MarkNew
+	MarkFirstEpisode
+	MarkFinal
+	MarkLive
+	MarkSale
+	MarkVoice
+	MarkDubbed
+	MarkPPV
+)
+
+const markFirstCell = 48
+
+// markOf gives the mark of the gaiji, and false if it is not a program mark
+func markOf(b0, b1 uint8) (Mark, bool) {
+	if b0 != 90 || b1 < markFirstCell || b1 > markFirstCell+34 || b1 == 64 || b1 == 65 {
+		return 0, false
+	}
+	return 1 << (b1 - markFirstCell), true
+}
+
+// Has reports whether all of the marks in other are in m
+func (m Mark) Has(other Mark) bool {
+	return m&other == other
+}
+
+// String gives the marks in the bracketed text form, like "[字][デ]"
+func (m Mark) String() string {
+	var sb strings.Builder
+	for i := uint8(0); i < 35; i++ {
+		if m&(1<<i) != 0 {
+			sb.WriteString(_gaijiSet[0][markFirstCell+i])
+		}
+	}
+	return sb.String()
+}
+
+// _gaijiUnicode is where ARIB STD-B62 gives a Unicode character different from the bracketed text in _gaijiSet
+var _gaijiUnicode = map[uint16]string{
+	0x7A50: "🅊", 0x7A51: "🅌", 0x7A52: "🄿", 0x7A53: "🅆", 0x7A54: "🅋", 0x7A55: "🈐", 0x7A56: "🈑", 0x7A57: "🈒",
+	0x7A58: "🈓", 0x7A59: "🅂", 0x7A5A: "🈔", 0x7A5B: "🈕", 0x7A5C: "🈖", 0x7A5D: "🅍", 0x7A5E: "🄱", 0x7A5F: "🄽",
+	0x7A62: "🈗", 0x7A63: "🈘", 0x7A64: "🈙", 0x7A65: "🈚", 0x7A66: "🈛", 0x7A67: "⚿", 0x7A68: "🈜", 0x7A69: "🈝",
+	0x7A6A: "🈞", 0x7A6B: "🈟", 0x7A6C: "🈠", 0x7A6D: "🈡", 0x7A6E: "🈢", 0x7A6F: "🈣", 0x7A70: "🈤", 0x7A71: "🈥",
+	0x7A72: "🅎", 0x7A74: "🈀",
+	0x7C30: "🄀", 0x7C31: "⒈", 0x7C32: "⒉", 0x7C33: "⒊", 0x7C34: "⒋", 0x7C35: "⒌", 0x7C36: "⒍", 0x7C37: "⒎",
+	0x7C38: "⒏", 0x7C39: "⒐",
+	0x7C40: "🄁", 0x7C41: "🄂", 0x7C42: "🄃", 0x7C43: "🄄", 0x7C44: "🄅", 0x7C45: "🄆", 0x7C46: "🄇", 0x7C47: "🄈",
+	0x7C48: "🄉", 0x7C49: "🄊",
+	0x7E31: "⑴", 0x7E32: "⑵", 0x7E33: "⑶", 0x7E34: "⑷", 0x7E35: "⑸", 0x7E36: "⑹", 0x7E37: "⑺", 0x7E38: "⑻",
+	0x7E39: "⑼", 0x7E3A: "⑽", 0x7E3B: "⑾", 0x7E3C: "⑿",
+}
+
+// _gaijiASCII is the ASCII text of the symbols which have no ASCII form by NFKC
+var _gaijiASCII = map[uint16]string{
+	0x7A55: "[Sign]", 0x7A56: "[Sub]", 0x7A57: "[Int]", 0x7A58: "[Data]", 0x7A5A: "[Bil]", 0x7A5B: "[Multi]", 0x7A5C: "[Com]",
+	0x7A62: "[Weather]", 0x7A63: "[Traffic]", 0x7A64: "[Movie]", 0x7A65: "[Free]", 0x7A66: "[Pay]", 0x7A67: "[Age]",
+	0x7A68: "[Pt1]", 0x7A69: "[Pt2]", 0x7A6A: "[Re]", 0x7A6B: "[New]", 0x7A6C: "[Ep1]", 0x7A6D: "[Final]",
+	0x7A6E: "[Live]", 0x7A6F: "[Sale]", 0x7A70: "[Voice]", 0x7A71: "[Dub]", 0x7A74: " etc.",
+}
+
+// renderGaiji renders the gaiji by the options of the decoder
+func (d *Decoder) renderGaiji(b0, b1 uint8) string {
+	code := uint16(b0+0x20)<<8 | uint16(b1+0x20)
+	if text, ok := d.GaijiTable[code]; ok {
+		return text
+	}
+	if d.CollectMarks {
+		if mark, ok := markOf(b0, b1); ok {
+			d.marks |= mark
+			return ""
+		}
+	}
+	text, ok := lookupGaiji(b0, b1)
+	if !ok {
+		return d.unassignedGaiji(code)
+	}
+	if b0 < 90 {
+		// additional kanji are plain characters, not symbols
+		return text
+	}
+	switch d.GaijiMode {
+	case GaijiUnicode:
+		if unicode, ok := _gaijiUnicode[code]; ok {
+			return unicode
+		}
+	case GaijiASCII:
+		if ascii, ok := _gaijiASCII[code]; ok {
+			return ascii
+		}
+		text = norm.NFKC.String(text)
+		for i := 0; i < len(text); i++ {
+			if text[i] >= 0x80 {
+				return ""
+			}
+		}
+	case GaijiRemove:
+		return ""
+	}
+	return text
+}
+
+// unassignedGaiji gives what is shown for the gaiji having no character, by the gaiji mode and Placeholder
+func (d *Decoder) unassignedGaiji(code uint16) string {
+	switch d.GaijiMode {
+	case GaijiASCII, GaijiRemove:
+		return ""
+	case GaijiUnicode:
+		if d.Placeholder == "" {
+			return "\uFFFD"
+		}
+		return d.Placeholder
+	}
+	if d.Placeholder == "" {
+		return fmt.Sprintf("{gaiji %04x}", code)
+	}
+	return d.Placeholder
+}
+
+// Marks returns the program marks collected with CollectMarks since the last Reset
+func (d *Decoder) Marks() Mark {
+	return d.marks
+}
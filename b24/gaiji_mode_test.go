@@ -0,0 +1,132 @@
+package b24
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGaijiMode(t *testing.T) {
+	// "[新]仮面" followed by [デ][字], with the gaiji set designated to G0
+	testVector := []byte{27, 36, 59, 15, 122, 107, 27, 36, 57, 15, 50, 62, 76, 76, 27, 36, 59, 15, 122, 88, 122, 86}
+	expected := map[GaijiMode]string{
+		GaijiText:    "[新]仮面[デ][字]",
+		GaijiUnicode: "🈟仮面🈓🈑",
+		GaijiASCII:   "[New]仮面[Data][Sub]",
+		GaijiRemove:  "仮面",
+	}
+	for mode, want := range expected {
+		decoder := NewDecoder(SIInitialState)
+		decoder.GaijiMode = mode
+		decoded, err := decoder.Decode(testVector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != want {
+			t.Fatalf("result in %s mode is not what expected: %s", mode, decoded)
+		}
+	}
+
+	decoder := NewDecoder(SIInitialState)
+	decoder.CollectMarks = true
+	decoded, err := decoder.Decode(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "仮面" || decoder.Marks() != MarkNew|MarkData|MarkCaption {
+		t.Fatalf("result is not what expected: %s %s", decoded, decoder.Marks())
+	}
+	if decoder.Marks().String() != "[字][デ][新]" || !decoder.Marks().Has(MarkCaption) || decoder.Marks().Has(MarkLive) {
+		t.Fatalf("unexpected marks: %s", decoder.Marks())
+	}
+	decoder.Reset()
+	if decoder.Marks() != 0 {
+		t.Fatal("marks should be cleared by reset")
+	}
+}
+
+func TestLoadGaijiTable(t *testing.T) {
+	table, err := LoadGaijiTable(strings.NewReader("# comment\n7A56 (字幕)\n\n7A58\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoder := NewDecoder(SIInitialState)
+	decoder.GaijiTable = table
+	decoded, err := decoder.Decode([]byte{27, 36, 59, 15, 122, 107, 122, 88, 122, 86})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "[新](字幕)" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+	if _, err := LoadGaijiTable(strings.NewReader("XYZ foo\n")); err == nil {
+		t.Fatal("illegal code should fail")
+	}
+}
+
+func TestGaijiModeParenthesizedAndNegativeNumbers(t *testing.T) {
+	// 94-17 (1), 94-28 (12), 94-81 ❶ and 94-92 ⓬
+	testVector := []byte{27, 36, 59, 15, 0x7e, 0x31, 0x7e, 0x3c, 0x7e, 0x71, 0x7e, 0x7c}
+	expected := map[GaijiMode]string{
+		GaijiText:    "(1)(12)❶⓬",
+		GaijiUnicode: "⑴⑿❶⓬",
+		GaijiASCII:   "(1)(12)",
+		GaijiRemove:  "",
+	}
+	for mode, want := range expected {
+		decoder := NewDecoder(SIInitialState)
+		decoder.GaijiMode = mode
+		decoded, err := decoder.Decode(testVector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != want {
+			t.Fatalf("result in %s mode is not what expected: %s", mode, decoded)
+		}
+	}
+}
+
+func TestGaijiUnicodeWithoutBrackets(t *testing.T) {
+	decoder := NewDecoder(SIInitialState)
+	decoder.GaijiMode = GaijiUnicode
+	for b0 := uint8(90); b0 <= 94; b0++ {
+		for b1 := uint8(1); b1 <= 94; b1++ {
+			if text := decoder.renderGaiji(b0, b1); strings.HasPrefix(text, "[") {
+				t.Fatalf("%d-%d is given as bracketed text in unicode mode: %s", b0, b1, text)
+			}
+		}
+	}
+}
+
+func TestGaijiModeUnassigned(t *testing.T) {
+	// 90-7, a road sign, between 仮 and 面, then 87-1 which is not defined
+	testVector := []byte{27, 36, 57, 15, 50, 62, 27, 36, 59, 15, 122, 39, 27, 36, 57, 15, 76, 76, 27, 36, 59, 15, 119, 33}
+	expected := map[GaijiMode]string{
+		GaijiText:    "仮{gaiji 7a27}面{gaiji 7721}",
+		GaijiUnicode: "仮\uFFFD面\uFFFD",
+		GaijiASCII:   "仮面",
+		GaijiRemove:  "仮面",
+	}
+	for mode, want := range expected {
+		decoder := NewDecoder(SIInitialState)
+		decoder.GaijiMode = mode
+		decoded, err := decoder.Decode(testVector)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if decoded != want {
+			t.Fatalf("result in %s mode is not what expected: %q", mode, decoded)
+		}
+	}
+
+	decoder := NewDecoder(SIInitialState)
+	decoder.Placeholder = "〓"
+	if decoded, _ := decoder.Decode(testVector); decoded != "仮〓面〓" {
+		t.Fatalf("result with placeholder is not what expected: %q", decoded)
+	}
+	decoder = NewDecoder(SIInitialState)
+	decoder.GaijiMode = GaijiASCII
+	decoder.Placeholder = "?"
+	if decoded, _ := decoder.Decode(testVector); decoded != "仮面" {
+		t.Fatalf("result in ascii mode with placeholder is not what expected: %q", decoded)
+	}
+}
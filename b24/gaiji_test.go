@@ -0,0 +1,40 @@
+package b24
+
+import "testing"
+
+func TestAdditionalKanji(t *testing.T) {
+	// 85-1, 85-94, 86-1 and 86-43 with the gaiji set designated to G0
+	decoded, err := NewDecoder(SIInitialState).Decode([]byte{27, 36, 59, 15, 0x75, 0x21, 0x75, 0x7e, 0x76, 0x21, 0x76, 0x4b})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "㐂鿅秚麵" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
+
+func TestGaijiSetCovered(t *testing.T) {
+	// road signs and symbols having no Unicode character
+	unmapped := map[[2]uint8]bool{
+		{90, 7}: true, {90, 12}: true, {90, 13}: true, {90, 14}: true, {90, 15}: true, {90, 18}: true, {90, 19}: true,
+		{90, 41}: true, {90, 42}: true, {90, 43}: true, {90, 44}: true, {93, 46}: true, {93, 47}: true, {93, 87}: true,
+	}
+	for b0 := uint8(90); b0 <= 94; b0++ {
+		for b1 := uint8(1); int(b1) < len(_gaijiSet[b0-90]); b1++ {
+			if text, ok := lookupGaiji(b0, b1); ok == unmapped[[2]uint8{b0, b1}] {
+				t.Fatalf("%d-%d is unexpectedly given as %q", b0, b1, text)
+			}
+		}
+	}
+}
+
+func TestGaijiFallback(t *testing.T) {
+	// 86-44 is after the last additional kanji, and row 87 is not defined
+	decoded, err := NewDecoder(SIInitialState).Decode([]byte{27, 36, 59, 15, 0x76, 0x4c, 0x77, 0x21})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "{gaiji 764c}{gaiji 7721}" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
@@ -0,0 +1,53 @@
+package b24
+
+// defaultMacros are the default macros of ARIB STD-B24 Table 7-20, invoked by 0x60-0x6F in the macro set.
+// Each designates G0-G3 with the macro set to G3, and invokes G0 to GL and G2 to GR.
+var defaultMacros = map[byte][]byte{
+	0x60: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x4A, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x61: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x31, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x62: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x20, 0x41, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x63: {0x1B, 0x28, 0x32, 0x1B, 0x29, 0x34, 0x1B, 0x2A, 0x35, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x64: {0x1B, 0x28, 0x32, 0x1B, 0x29, 0x33, 0x1B, 0x2A, 0x35, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x65: {0x1B, 0x28, 0x32, 0x1B, 0x29, 0x20, 0x41, 0x1B, 0x2A, 0x35, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x66: {0x1B, 0x28, 0x20, 0x41, 0x1B, 0x29, 0x20, 0x42, 0x1B, 0x2A, 0x20, 0x43, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x67: {0x1B, 0x28, 0x20, 0x44, 0x1B, 0x29, 0x20, 0x45, 0x1B, 0x2A, 0x20, 0x46, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x68: {0x1B, 0x28, 0x20, 0x47, 0x1B, 0x29, 0x20, 0x48, 0x1B, 0x2A, 0x20, 0x49, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x69: {0x1B, 0x28, 0x20, 0x4A, 0x1B, 0x29, 0x20, 0x4B, 0x1B, 0x2A, 0x20, 0x4C, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6A: {0x1B, 0x28, 0x20, 0x4D, 0x1B, 0x29, 0x20, 0x4E, 0x1B, 0x2A, 0x20, 0x4F, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6B: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x20, 0x42, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6C: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x20, 0x43, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6D: {0x1B, 0x24, 0x42, 0x1B, 0x29, 0x20, 0x44, 0x1B, 0x2A, 0x30, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6E: {0x1B, 0x28, 0x31, 0x1B, 0x29, 0x30, 0x1B, 0x2A, 0x4A, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+	0x6F: {0x1B, 0x28, 0x4A, 0x1B, 0x29, 0x32, 0x1B, 0x2A, 0x20, 0x41, 0x1B, 0x2B, 0x20, 0x70, 0x0F, 0x1B, 0x7D},
+}
+
+// maxMacroDepth limits macros invoking macros
+const maxMacroDepth = 4
+
+// macroBody gives the body of the macro, the user-defined one taking precedence over the default one
+func (d *Decoder) macroBody(code byte) ([]byte, bool) {
+	if body, ok := d.macros[code]; ok {
+		return body, true
+	}
+	body, ok := defaultMacros[code]
+	return body, ok
+}
+
+// DefineMacro defines the macro of code, as MACRO definitions in the text do
+func (d *Decoder) DefineMacro(code byte, body []byte) {
+	if d.macros == nil {
+		d.macros = map[byte][]byte{}
+	}
+	d.macros[code&0x7F] = append([]byte(nil), body...)
+}
+
+// invokeMacro decodes the body of the macro of code
+func (d *Decoder) invokeMacro(code byte) error {
+	body, ok := d.macroBody(code & 0x7F)
+	if !ok || d.macroDepth >= maxMacroDepth {
+		return nil
+	}
+	d.macroDepth++
+	defer func() { d.macroDepth-- }()
+	return d.decode(body)
+}
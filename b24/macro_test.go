@@ -0,0 +1,37 @@
+package b24
+
+import "testing"
+
+func TestDefaultMacro(t *testing.T) {
+	decoder := NewDecoder(CaptionInitialState)
+	// SS3 0x6E designates katakana, hiragana and alphabet to G0-G2
+	decoded, err := decoder.Decode([]byte{0x1d, 0x6e, 0x22, 0xc1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "アA" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
+
+func TestUserDefinedMacro(t *testing.T) {
+	decoder := NewDecoder(CaptionInitialState)
+	// defines macro 0x21 designating alphabet to G0, then invokes it by SS3
+	decoded, err := decoder.Decode([]byte{0x95, 0x40, 0x21, 0x1b, 0x28, 0x4a, 0x95, 0x4f, 0x30, 0x21, 0x1d, 0x21, 0x42})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "亜B" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+
+	// definition to be executed at once, overriding a default macro
+	decoder.Reset()
+	decoded, err = decoder.Decode([]byte{0x95, 0x41, 0x60, 0x1b, 0x28, 0x4a, 0x95, 0x4f, 0x43, 0x1d, 0x60, 0x44})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "CD" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+}
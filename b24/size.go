@@ -0,0 +1,85 @@
+package b24
+
+import (
+	"fmt"
+	"strings"
+
+	"golang.org/x/text/width"
+)
+
+// CharacterSize is the character size set by SSZ, MSZ, NSZ and SZX
+type CharacterSize uint8
+
+const (
+	SizeNormal CharacterSize = iota
+	SizeMiddle
+	SizeSmall
+	SizeTiny
+	SizeDoubleHeight
+	SizeDoubleWidth
+	SizeDoubleHeightWidth
+	SizeSpecial1
+	SizeSpecial2
+)
+
+func (s CharacterSize) String() string {
+	switch s {
+	case SizeNormal:
+		return "normal"
+	case SizeMiddle:
+		return "middle"
+	case SizeSmall:
+		return "small"
+	case SizeTiny:
+		return "tiny"
+	case SizeDoubleHeight:
+		return "double height"
+	case SizeDoubleWidth:
+		return "double width"
+	case SizeDoubleHeightWidth:
+		return "double height and width"
+	case SizeSpecial1:
+		return "special 1"
+	case SizeSpecial2:
+		return "special 2"
+	}
+	return fmt.Sprintf("CharacterSize(%d)", uint8(s))
+}
+
+// sizeOfSZX gives the character size by the parameter of SZX
+func sizeOfSZX(p uint8) (CharacterSize, bool) {
+	switch p {
+	case 0x60:
+		return SizeTiny, true
+	case 0x41:
+		return SizeDoubleHeight, true
+	case 0x44:
+		return SizeDoubleWidth, true
+	case 0x45:
+		return SizeDoubleHeightWidth, true
+	case 0x6B:
+		return SizeSpecial1, true
+	case 0x64:
+		return SizeSpecial2, true
+	}
+	return SizeNormal, false
+}
+
+// halfWidthAlphanumeric turns full-width ASCII variants and the ideographic space into ASCII,
+// as they are shown in middle size. Kana are kept as is.
+func halfWidthAlphanumeric(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '　':
+			return ' '
+		case '！' <= r && r <= '～':
+			return r - 0xFEE0
+		}
+		return r
+	}, s)
+}
+
+// FoldWidth folds the widths of s consistently, full-width alphanumerics to ASCII and half-width katakana to full-width
+func FoldWidth(s string) string {
+	return width.Fold.String(s)
+}
@@ -0,0 +1,37 @@
+package b24
+
+import "testing"
+
+func TestDecodeMiddleSize(t *testing.T) {
+	// 第 MSZ １ NSZ 話, ＮＨＫ in kanji set
+	testVector := []byte{0x42, 0x68, 0x89, 0x23, 0x31, 0x8a, 0x4f, 0x43, 0x23, 0x4e, 0x23, 0x48, 0x23, 0x4b}
+	decoder := NewDecoder(SIInitialState)
+	decoded, err := decoder.Decode(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "第1話ＮＨＫ" {
+		t.Fatalf("result is not what expected: %s", decoded)
+	}
+	if decoder.Size() != SizeNormal {
+		t.Fatalf("unexpected size: %s", decoder.Size())
+	}
+
+	decoder.Reset()
+	decoder.NormalizeWidth = true
+	decoded, err = decoder.Decode(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "第1話NHK" {
+		t.Fatalf("normalized result is not what expected: %s", decoded)
+	}
+
+	// SZX with double height
+	if _, err = decoder.Decode([]byte{0x8b, 0x41}); err != nil {
+		t.Fatal(err)
+	}
+	if decoder.Size() != SizeDoubleHeight {
+		t.Fatalf("unexpected size: %s", decoder.Size())
+	}
+}
@@ -0,0 +1,30 @@
+package b24
+
+// Charset is a graphic set which can be designated to G0-G3
+type Charset = b24GCharset
+
+const (
+	CharsetKanji         = b24CSKanji
+	CharsetAlphabet      = b24CSAlphabet
+	CharsetHiragana      = b24CSHiragana
+	CharsetKatakana      = b24CSKatakana
+	CharsetX0201Katakana = b24CSX0201Katakana
+	CharsetX0213Plane1   = b24CSX0213Plane1
+	CharsetX0213Plane2   = b24CSX0213Plane2
+	CharsetGaiji         = b24CSGaiji
+	CharsetMacro         = b24CSMacro
+)
+
+// InitialState is the designation of G0-G3 and invocation of GL and GR, given by indices of G, before decoding
+type InitialState struct {
+	G  [4]Charset
+	GL int
+	GR int
+}
+
+var (
+	// SIInitialState is the initial state of SI text, e.g. EIT and SDT
+	SIInitialState = InitialState{[4]Charset{CharsetKanji, CharsetAlphabet, CharsetHiragana, CharsetKatakana}, 0, 2}
+	// CaptionInitialState is the initial state of caption and superimpose text
+	CaptionInitialState = InitialState{[4]Charset{CharsetKanji, CharsetAlphabet, CharsetHiragana, CharsetMacro}, 0, 2}
+)
@@ -0,0 +1,134 @@
+package b24
+
+import "image/color"
+
+// Flashing is the flashing state set by FLC
+type Flashing uint8
+
+const (
+	FlashingNone Flashing = iota
+	FlashingNormal
+	FlashingInverted
+)
+
+func flashingOf(p uint8) Flashing {
+	switch p {
+	case 0x40:
+		return FlashingNormal
+	case 0x47:
+		return FlashingInverted
+	}
+	return FlashingNone
+}
+
+// Polarity is the pattern polarity set by POL
+type Polarity uint8
+
+const (
+	PolarityNormal Polarity = iota
+	PolarityInverted1
+	PolarityInverted2
+)
+
+// Highlight is the set of sides of the enclosure set by HLC
+type Highlight uint8
+
+const (
+	HighlightBottom Highlight = 1 << iota
+	HighlightRight
+	HighlightTop
+	HighlightLeft
+)
+
+// Style is how characters are displayed, with colors given by indices of DefaultCLUT
+type Style struct {
+	Foreground     uint8
+	Background     uint8
+	HalfForeground uint8
+	HalfBackground uint8
+	Size           CharacterSize
+	Flashing       Flashing
+	Polarity       Polarity
+	Highlight      Highlight
+}
+
+// DefaultStyle is the style before any control code, white characters on the transparent background
+var DefaultStyle = Style{Foreground: 7, Background: 8, HalfForeground: 7, HalfBackground: 8}
+
+func (s Style) ForegroundColor() color.Color {
+	return DefaultCLUT[s.Foreground]
+}
+
+func (s Style) BackgroundColor() color.Color {
+	return DefaultCLUT[s.Background]
+}
+
+// Position is the active position, in rows and columns of the display area
+type Position struct {
+	Row    int
+	Column int
+}
+
+// Run is text displayed in the same style
+type Run struct {
+	Style
+	// Position is the active position set by APS before the text, valid if HasPosition
+	Position    Position
+	HasPosition bool
+	// Advance is the number of characters the active position is moved forward by PAPF before the text
+	Advance int
+	Text    string
+}
+
+// emit appends text in the current style to the runs
+func (d *Decoder) emit(text string) {
+	if text == "" {
+		return
+	}
+	if n := len(d.runs); n > 0 {
+		last := &d.runs[n-1]
+		if last.Text == "" {
+			// a run holding only a position change takes the style of its text
+			last.Style = d.style
+		}
+		if last.Style == d.style {
+			last.Text += text
+			return
+		}
+	}
+	d.runs = append(d.runs, Run{Style: d.style, Text: text})
+}
+
+// positionRun gives the run to hold an active position change, which is empty until text comes
+func (d *Decoder) positionRun() *Run {
+	if n := len(d.runs); n == 0 || d.runs[n-1].Text != "" {
+		d.runs = append(d.runs, Run{Style: d.style})
+	}
+	return &d.runs[len(d.runs)-1]
+}
+
+func (d *Decoder) moveTo(position Position) {
+	run := d.positionRun()
+	run.Position = position
+	run.HasPosition = true
+	run.Advance = 0
+}
+
+func (d *Decoder) advance(columns int) {
+	d.positionRun().Advance += columns
+}
+
+// setColor sets a color by the parameter of COL
+func (d *Decoder) setColor(p uint8) {
+	index := d.palette<<4 | p&0x0f
+	switch p & 0xf0 {
+	case 0x40:
+		d.style.Foreground = index
+	case 0x50:
+		d.style.Background = index
+	case 0x60:
+		d.style.HalfForeground = index
+	case 0x70:
+		d.style.HalfBackground = index
+	}
+}
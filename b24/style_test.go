@@ -0,0 +1,77 @@
+package b24
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDecodeStyled(t *testing.T) {
+	testVector := []byte{
+		0x1c, 0x41, 0x42, // APS row 1 column 2
+		0x81, 0xa2, // RDF あ
+		0x90, 0x51, 0xa4, // COL background 1, い
+		0x91, 0x40, 0xa6, // FLC normal, う
+		0x16, 0x43, 0x90, 0x20, 0x01, 0x90, 0x43, 0x8b, 0x41, 0xa8, // PAPF 3, COL palette 1, COL foreground 3, SZX double height, え
+	}
+	decoder := NewDecoder(CaptionInitialState)
+	runs, err := decoder.DecodeStyled(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	red := DefaultStyle
+	red.Foreground = 1
+	redOnRed := red
+	redOnRed.Background = 1
+	flashing := redOnRed
+	flashing.Flashing = FlashingNormal
+	last := flashing
+	last.Foreground = 0x13
+	last.Size = SizeDoubleHeight
+	expected := []Run{
+		{Style: red, Position: Position{1, 2}, HasPosition: true, Text: "あ"},
+		{Style: redOnRed, Text: "い"},
+		{Style: flashing, Text: "う"},
+		{Style: last, Advance: 3, Text: "え"},
+	}
+	if !reflect.DeepEqual(runs, expected) {
+		t.Fatalf("result is not what expected: %+v", runs)
+	}
+
+	decoder.Reset()
+	decoded, err := decoder.Decode(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "あいうえ" {
+		t.Fatalf("plain result is not what expected: %s", decoded)
+	}
+}
+
+func TestDecodeCSI(t *testing.T) {
+	// CS, SDF 620;480, SDP 170;30, SVS 24, あい
+	testVector := []byte{0x0c, 0x9b, '6', '2', '0', ';', '4', '8', '0', 0x20, 0x56, 0x9b, '1', '7', '0', ';', '3', '0', 0x20, 0x5f, 0x9b, '2', '4', 0x20, 0x59, 0xa2, 0xa4}
+	decoder := NewDecoder(CaptionInitialState)
+	decoded, err := decoder.Decode(testVector)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != "\fあい" {
+		t.Fatalf("result is not what expected: %q", decoded)
+	}
+	expected := DefaultDisplayFormat
+	expected.Width, expected.Height, expected.X, expected.Y = 620, 480, 170, 30
+	if decoder.Format() != expected {
+		t.Fatalf("unexpected display format: %+v", decoder.Format())
+	}
+	if x, y := decoder.Format().Dot(Position{Row: 7, Column: 4}, SizeNormal); x != 332 || y != 498 {
+		t.Fatalf("unexpected dot: %d, %d", x, y)
+	}
+
+	// truncated CSI is skipped in lenient mode
+	decoder.Reset()
+	decoder.Lenient = true
+	decoded, _ = decoder.Decode([]byte{0xa2, 0x9b, '2', '4'})
+	if decoded != "あ\uFFFD" || len(decoder.Warnings()) != 1 {
+		t.Fatalf("unexpected lenient result: %q %v", decoded, decoder.Warnings())
+	}
+}
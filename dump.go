@@ -0,0 +1,186 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/zlm2012/wildwrap/ts"
+)
+
+// dumpRecord is a section in NDJSON output
+type dumpRecord struct {
+	Offset  int64      `json:"offset"`
+	PID     uint16     `json:"pid"`
+	TableID ts.TableID `json:"table_id"`
+	Type    string     `json:"type"`
+	Frame   ts.Frame   `json:"frame"`
+}
+
+// parseUintList parses comma separated numbers, decimal or hex with 0x
+func parseUintList(list string, bitSize int) (map[uint64]bool, error) {
+	if list == "" {
+		return nil, nil
+	}
+	values := map[uint64]bool{}
+	for _, item := range strings.Split(list, ",") {
+		value, err := strconv.ParseUint(strings.TrimSpace(item), 0, bitSize)
+		if err != nil {
+			return nil, err
+		}
+		values[value] = true
+	}
+	return values, nil
+}
+
+// runDump prints every PSI/SI section parsed by ts.Decoder
+func runDump(args []string) {
+	flags := flag.NewFlagSet("dump", flag.ExitOnError)
+	pids := flags.String("pid", "", "comma separated PIDs to dump, e.g. 0x11,0x12")
+	tables := flags.String("table", "", "comma separated table IDs to dump, e.g. 0x42,0x4e")
+	format := flags.String("format", "text", "output format, text or ndjson")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s dump [options] <file.ts|->\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 || (*format != "text" && *format != "ndjson") {
+		flags.Usage()
+		os.Exit(2)
+	}
+	pidFilter, err := parseUintList(*pids, 13)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	tableFilter, err := parseUintList(*tables, 8)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	input, err := openLiveInput(flags.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer input.Close()
+
+	out := bufio.NewWriter(os.Stdout)
+	defer out.Flush()
+	encoder := json.NewEncoder(out)
+	decoder := ts.NewDecoder(input)
+	for {
+		frame, err := decoder.ParseNext()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return
+		}
+		var readErr *ts.ReadError
+		if errors.As(err, &readErr) {
+			out.Flush()
+			log.Fatalln(err)
+		}
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		info := decoder.LastSection()
+		if (pidFilter != nil && !pidFilter[uint64(info.PID)]) || (tableFilter != nil && !tableFilter[uint64(info.TableID)]) {
+			continue
+		}
+		if *format == "ndjson" {
+			if err := encoder.Encode(dumpRecord{info.Offset, info.PID, ts.TableID(info.TableID), frame.GetType(), frame}); err != nil {
+				log.Fatalln(err)
+			}
+			continue
+		}
+		fmt.Fprintf(out, "offset %d (0x%x) PID 0x%04x table 0x%02x %s\n", info.Offset, info.Offset, info.PID, info.TableID, frame.GetType())
+		dumpValue(out, 1, reflect.ValueOf(frame).Elem())
+		out.WriteString("\n")
+	}
+}
+
+var (
+	stringerType = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	timeType     = reflect.TypeOf(time.Time{})
+)
+
+// dumpScalar gives the value in one line if it is not a struct, a slice nor a map to be expanded
+func dumpScalar(v reflect.Value) (string, bool) {
+	if v.Type() == timeType {
+		return v.Interface().(time.Time).Format(time.RFC3339), true
+	}
+	if v.Type().Implements(stringerType) {
+		return v.Interface().(fmt.Stringer).String(), true
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return fmt.Sprintf("% x", v.Bytes()), true
+		}
+		return "", false
+	case reflect.Struct, reflect.Map, reflect.Ptr, reflect.Interface:
+		return "", false
+	case reflect.String:
+		return strconv.Quote(v.String()), true
+	case reflect.Uint16:
+		return fmt.Sprintf("%d (0x%04x)", v.Uint(), v.Uint()), true
+	}
+	return fmt.Sprint(v.Interface()), true
+}
+
+// dumpValue writes the fields of the struct indented, leaving out empty slices, maps and nil pointers
+func dumpValue(w io.Writer, depth int, v reflect.Value) {
+	indent := strings.Repeat("  ", depth)
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		dumpField(w, depth, indent+field.Name, v.Field(i))
+	}
+}
+
+func dumpField(w io.Writer, depth int, name string, v reflect.Value) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if (v.Kind() == reflect.Slice || v.Kind() == reflect.Map) && v.Len() == 0 {
+		return
+	}
+	if s, ok := dumpScalar(v); ok {
+		fmt.Fprintf(w, "%s: %s\n", name, s)
+		return
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		fmt.Fprintf(w, "%s:\n", name)
+		dumpValue(w, depth+1, v)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			dumpField(w, depth, fmt.Sprintf("%s[%d]", name, i), v.Index(i))
+		}
+	case reflect.Map:
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			switch keys[i].Kind() {
+			case reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+				return keys[i].Uint() < keys[j].Uint()
+			}
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			k, _ := dumpScalar(key)
+			dumpField(w, depth, fmt.Sprintf("%s[%s]", name, k), v.MapIndex(key))
+		}
+	}
+}
@@ -2,4 +2,14 @@ module github.com/zlm2012/wildwrap
 
 go 1.17
 
-require github.com/zlm2012/wildwrap/ts v0.0.0-20220214113810-3f5cc6d33caa // indirect
+require (
+	github.com/zlm2012/wildwrap/b24 v0.0.0-20220323164031-7a27ae70bbd3
+	github.com/zlm2012/wildwrap/ts v0.0.0-20220214113810-3f5cc6d33caa
+)
+
+require golang.org/x/text v0.3.7
+
+replace (
+	github.com/zlm2012/wildwrap/b24 => ./b24
+	github.com/zlm2012/wildwrap/ts => ./ts
+)
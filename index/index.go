@@ -0,0 +1,221 @@
+// Package index is a full text search index of caption cues across recordings, stored in a single file
+package index
+
+import (
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// formatVersion is written at the head of the index file, and changed when Entry changes incompatibly
+const formatVersion = 1
+
+// Entry is a caption cue in a recording
+type Entry struct {
+	// Recording is the path of the TS file
+	Recording string
+	ServiceID uint16
+	// EventID and EventName are of the present event when the cue starts, or zero if no EIT p/f came before
+	EventID   uint16
+	EventName string
+	// Offset is the start of the cue from the beginning of the recording
+	Offset time.Duration
+	// Time is the wall-clock time of the start by TOT, or zero if no TOT came before
+	Time     time.Time
+	Language string
+	Text     string
+}
+
+// Index is the caption cues of recordings, with the postings of bigrams in the normalized text
+type Index struct {
+	path       string
+	entries    []Entry
+	normalized []string
+	postings   map[string][]int
+}
+
+type indexFile struct {
+	Version int
+	Entries []Entry
+}
+
+// Open loads the index file, or gives an empty index to be saved to the path if the file does not exist
+func Open(path string) (*Index, error) {
+	ix := &Index{path: path}
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		ix.rebuild()
+		return ix, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var content indexFile
+	if err := gob.NewDecoder(f).Decode(&content); err != nil {
+		return nil, errors.New(fmt.Sprintf("broken index file %s: %v", path, err))
+	}
+	if content.Version != formatVersion {
+		return nil, errors.New(fmt.Sprintf("index file %s has version %d, expected %d", path, content.Version, formatVersion))
+	}
+	ix.entries = content.Entries
+	ix.rebuild()
+	return ix, nil
+}
+
+// Save writes the index to its path, replacing the file at once
+func (ix *Index) Save() error {
+	tmp, err := os.CreateTemp(filepath.Dir(ix.path), filepath.Base(ix.path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	err = gob.NewEncoder(tmp).Encode(indexFile{formatVersion, ix.entries})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), ix.path)
+}
+
+func (ix *Index) rebuild() {
+	ix.normalized = make([]string, len(ix.entries))
+	ix.postings = map[string][]int{}
+	for i := range ix.entries {
+		ix.post(i)
+	}
+}
+
+func (ix *Index) post(i int) {
+	ix.normalized[i] = Normalize(ix.entries[i].Text)
+	for _, gram := range bigrams(ix.normalized[i]) {
+		ix.postings[gram] = append(ix.postings[gram], i)
+	}
+}
+
+// Len gives the number of entries
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+// Recordings gives the paths of the indexed recordings in order
+func (ix *Index) Recordings() []string {
+	seen := map[string]bool{}
+	var recordings []string
+	for _, entry := range ix.entries {
+		if !seen[entry.Recording] {
+			seen[entry.Recording] = true
+			recordings = append(recordings, entry.Recording)
+		}
+	}
+	sort.Strings(recordings)
+	return recordings
+}
+
+// Add puts the entries of the recording, replacing those indexed before for it
+func (ix *Index) Add(recording string, entries []Entry) {
+	ix.Remove(recording)
+	for _, entry := range entries {
+		entry.Recording = recording
+		ix.entries = append(ix.entries, entry)
+		ix.normalized = append(ix.normalized, "")
+		ix.post(len(ix.entries) - 1)
+	}
+}
+
+// Remove drops the entries of the recording
+func (ix *Index) Remove(recording string) {
+	kept := ix.entries[:0]
+	for _, entry := range ix.entries {
+		if entry.Recording != recording {
+			kept = append(kept, entry)
+		}
+	}
+	if len(kept) != len(ix.entries) {
+		ix.entries = kept
+		ix.rebuild()
+	}
+}
+
+// Search gives the entries containing all the words of the query, separated by spaces, in the order of recording and offset.
+// Hiragana and katakana, full width and half width, and upper and lower case are not distinguished.
+func (ix *Index) Search(query string) []Entry {
+	var words []string
+	for _, word := range strings.Fields(query) {
+		if word = Normalize(word); word != "" {
+			words = append(words, word)
+		}
+	}
+	if len(words) == 0 {
+		return nil
+	}
+	var results []Entry
+	for _, i := range ix.candidates(words) {
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(ix.normalized[i], word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			results = append(results, ix.entries[i])
+		}
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		if results[i].Recording != results[j].Recording {
+			return results[i].Recording < results[j].Recording
+		}
+		return results[i].Offset < results[j].Offset
+	})
+	return results
+}
+
+// candidates gives the entries having all the bigrams of the words, or all entries if no word has a bigram
+func (ix *Index) candidates(words []string) []int {
+	var candidates []int
+	narrowed := false
+	for _, word := range words {
+		for _, gram := range bigrams(word) {
+			postings := ix.postings[gram]
+			if !narrowed {
+				candidates = append([]int(nil), postings...)
+				narrowed = true
+				continue
+			}
+			candidates = intersect(candidates, postings)
+		}
+	}
+	if !narrowed {
+		candidates = make([]int, len(ix.entries))
+		for i := range candidates {
+			candidates[i] = i
+		}
+	}
+	return candidates
+}
+
+// intersect gives the common indices of the sorted postings
+func intersect(a, b []int) []int {
+	var common []int
+	for i, j := 0, 0; i < len(a) && j < len(b); {
+		switch {
+		case a[i] < b[j]:
+			i++
+		case a[i] > b[j]:
+			j++
+		default:
+			common = append(common, a[i])
+			i++
+			j++
+		}
+	}
+	return common
+}
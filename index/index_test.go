@@ -0,0 +1,70 @@
+package index
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestNormalize(t *testing.T) {
+	cases := map[string]string{
+		"カタカナ":    "かたかな",
+		"ｶﾀｶﾅ":    "かたかな",
+		"ｶﾞｯｺｳ":   "がっこう",
+		"ＡＢＣ　１２３": "abc123",
+		"ヽヾ":      "ゝゞ",
+		"長音ー":     "長音ー",
+	}
+	for in, expected := range cases {
+		if actual := Normalize(in); actual != expected {
+			t.Errorf("Normalize(%q) = %q, expected %q", in, actual, expected)
+		}
+	}
+}
+
+func TestSearch(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "captions.idx")
+	ix, err := Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ix.Add("a.ts", []Entry{
+		{ServiceID: 1024, EventID: 1, Offset: 3 * time.Second, Language: "jpn", Text: "きょうは　いい天気ですね"},
+		{ServiceID: 1024, EventID: 1, Offset: time.Second, Language: "jpn", Text: "ニュースの時間です"},
+	})
+	ix.Add("b.ts", []Entry{
+		{ServiceID: 1032, EventID: 7, Offset: 2 * time.Second, Language: "jpn", Text: "ＮＨＫ　にゅーす"},
+	})
+	if err := ix.Save(); err != nil {
+		t.Fatal(err)
+	}
+	ix, err = Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ix.Len() != 3 {
+		t.Fatalf("%d entries after reopening, expected 3", ix.Len())
+	}
+
+	results := ix.Search("にゅーす")
+	if len(results) != 2 || results[0].Recording != "a.ts" || results[0].Offset != time.Second || results[1].Recording != "b.ts" {
+		t.Errorf("unexpected results of hiragana query: %+v", results)
+	}
+	if results := ix.Search("ｲｲ 天気"); len(results) != 1 || results[0].Offset != 3*time.Second {
+		t.Errorf("unexpected results of half width katakana query: %+v", results)
+	}
+	if results := ix.Search("nhk"); len(results) != 1 || results[0].ServiceID != 1032 {
+		t.Errorf("unexpected results of ASCII query: %+v", results)
+	}
+	if results := ix.Search("天"); len(results) != 1 {
+		t.Errorf("unexpected results of single character query: %+v", results)
+	}
+	if results := ix.Search("天気 ニュース"); len(results) != 0 {
+		t.Errorf("unexpected results of words in different cues: %+v", results)
+	}
+
+	ix.Add("a.ts", []Entry{{Offset: time.Minute, Text: "さようなら"}})
+	if ix.Len() != 2 || len(ix.Search("ニュース")) != 1 || len(ix.Search("サヨウナラ")) != 1 {
+		t.Errorf("entries of a.ts are not replaced: %v", ix.Recordings())
+	}
+}
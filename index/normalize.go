@@ -0,0 +1,44 @@
+package index
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+	"golang.org/x/text/width"
+)
+
+// Normalize folds the text for matching: full width alphanumerics and half width katakana to the usual width,
+// katakana to hiragana, upper case to lower case, and spaces are removed
+func Normalize(s string) string {
+	s = norm.NFC.String(width.Fold.String(s))
+	var sb strings.Builder
+	for _, r := range s {
+		switch {
+		case unicode.IsSpace(r):
+			continue
+		case r >= 'ァ' && r <= 'ヶ', r == 'ヽ' || r == 'ヾ':
+			// katakana to hiragana, including the iteration marks
+			r -= 'ァ' - 'ぁ'
+		default:
+			r = unicode.ToLower(r)
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// bigrams gives the distinct pairs of adjacent runes in the normalized text
+func bigrams(s string) []string {
+	runes := []rune(s)
+	seen := map[string]bool{}
+	var grams []string
+	for i := 0; i+1 < len(runes); i++ {
+		gram := string(runes[i : i+2])
+		if !seen[gram] {
+			seen[gram] = true
+			grams = append(grams, gram)
+		}
+	}
+	return grams
+}
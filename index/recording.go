@@ -0,0 +1,49 @@
+package index
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/zlm2012/wildwrap/ts"
+)
+
+// ExtractEntries reads the caption cues of the TS file as entries to be added for it.
+// The service is selected like ts.CaptionExtractor, the first one with captions if serviceID is 0.
+func ExtractEntries(path string, serviceID uint16) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	extractor := ts.NewCaptionExtractor(f)
+	extractor.ServiceID = serviceID
+	var entries []Entry
+	extractor.OnCue = func(cue ts.CaptionCue) {
+		entry := Entry{
+			Recording: path,
+			ServiceID: extractor.ServiceID,
+			Offset:    cue.Start,
+			Language:  extractor.LanguageCode(cue.Language),
+			Text:      cue.Text(),
+		}
+		if event := extractor.Event; event != nil {
+			entry.EventID = event.EventID
+			entry.EventName = event.ShortDescriptor.EventName
+		}
+		if wallClock, ok := extractor.WallClockAt(cue.Start); ok {
+			entry.Time = wallClock
+		}
+		entries = append(entries, entry)
+	}
+	if _, err := extractor.Extract(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// FormatOffset gives the offset like 01:02:03.450, to seek a player to
+func FormatOffset(d time.Duration) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
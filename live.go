@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+	"github.com/zlm2012/wildwrap/ts"
+)
+
+// liveRun is a run of caption text in the JSON event
+type liveRun struct {
+	Text       string `json:"text"`
+	Foreground string `json:"foreground"`
+	Background string `json:"background"`
+	Size       string `json:"size"`
+	Row        *int   `json:"row,omitempty"`
+	Column     *int   `json:"column,omitempty"`
+}
+
+// liveEvent is a caption event sent to the WebSocket clients.
+// Type is "cue" when a statement is presented, and "erase" when the screen of the language is cleared.
+type liveEvent struct {
+	Type     string `json:"type"`
+	Language string `json:"language"`
+	PTS      uint64 `json:"pts"`
+	// Offset is the time from the first video PTS
+	Offset string `json:"offset"`
+	// Time is the wall-clock time estimated by TOT, omitted until a TOT comes
+	Time string `json:"time,omitempty"`
+	// Clear tells the statement begins with clearing the screen by CS
+	Clear bool      `json:"clear,omitempty"`
+	Text  string    `json:"text,omitempty"`
+	Runs  []liveRun `json:"runs,omitempty"`
+}
+
+func clutHex(index uint8) string {
+	r, g, b, _ := b24.DefaultCLUT[index].RGBA()
+	return fmt.Sprintf("#%02X%02X%02X", r>>8, g>>8, b>>8)
+}
+
+// openLiveInput opens "-" as stdin, an http(s) URL as a stream, and others as a file or a named pipe
+func openLiveInput(input string) (io.ReadCloser, error) {
+	if input == "-" {
+		return os.Stdin, nil
+	}
+	if strings.HasPrefix(input, "http://") || strings.HasPrefix(input, "https://") {
+		resp, err := http.Get(input)
+		if err != nil {
+			return nil, err
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			return nil, errors.New(fmt.Sprintf("unexpected status of %s: %s", input, resp.Status))
+		}
+		return resp.Body, nil
+	}
+	return os.Open(input)
+}
+
+// newLiveEvent makes the event at the time of the extractor
+func newLiveEvent(e *ts.CaptionExtractor, eventType string, language uint8, at time.Duration) liveEvent {
+	event := liveEvent{
+		Type:     eventType,
+		Language: e.LanguageCode(language),
+		PTS:      e.PTSAt(at),
+		Offset:   at.String(),
+	}
+	if event.Language == "" {
+		event.Language = fmt.Sprintf("lang%d", language)
+	}
+	if wallClock, ok := e.WallClockAt(at); ok {
+		event.Time = wallClock.Format(time.RFC3339Nano)
+	}
+	return event
+}
+
+func cueEvent(e *ts.CaptionExtractor, cue ts.CaptionCue) liveEvent {
+	event := newLiveEvent(e, "cue", cue.Language, cue.Start)
+	event.Text = cue.Text()
+	for _, run := range cue.Runs {
+		if i := strings.LastIndex(run.Text, "\f"); i >= 0 {
+			// only the runs after the last CS are on the screen
+			event.Clear = true
+			event.Runs = nil
+			run.Text = run.Text[i+1:]
+		}
+		if run.Text == "" {
+			continue
+		}
+		r := liveRun{
+			Text:       run.Text,
+			Foreground: clutHex(run.Foreground),
+			Background: clutHex(run.Background),
+			Size:       run.Size.String(),
+		}
+		if run.HasPosition {
+			row, column := run.Position.Row, run.Position.Column
+			r.Row, r.Column = &row, &column
+		}
+		event.Runs = append(event.Runs, r)
+	}
+	return event
+}
+
+// runLive serves the captions of a live TS as JSON events over WebSocket
+func runLive(args []string) {
+	flags := flag.NewFlagSet("live", flag.ExitOnError)
+	listen := flags.String("listen", ":8080", "address to serve WebSocket on")
+	path := flags.String("path", "/captions", "path of WebSocket")
+	serviceID := flags.Uint("service", 0, "service ID, or the first service with captions if 0")
+	languages := flags.String("lang", "", "comma separated ISO 639 codes of caption languages, e.g. jpn,eng")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s live [options] <file.ts|pipe|-|http://...>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	input, err := openLiveInput(flags.Arg(0))
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer input.Close()
+
+	hub := newWSHub()
+	http.Handle(*path, hub)
+	go func() {
+		log.Fatalln(http.ListenAndServe(*listen, nil))
+	}()
+
+	extractor := ts.NewCaptionExtractor(input)
+	extractor.ServiceID = uint16(*serviceID)
+	if *languages != "" {
+		extractor.Languages = strings.Split(*languages, ",")
+	}
+	broadcast := func(language uint8, event liveEvent) {
+		if len(extractor.Languages) > 0 && !containsString(extractor.Languages, extractor.LanguageCode(language)) {
+			return
+		}
+		message, err := json.Marshal(event)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		hub.Broadcast(message)
+	}
+	extractor.OnCue = func(cue ts.CaptionCue) {
+		broadcast(cue.Language, cueEvent(extractor, cue))
+	}
+	extractor.OnErase = func(language uint8, at time.Duration) {
+		broadcast(language, newLiveEvent(extractor, "erase", language, at))
+	}
+	if err := extractor.Stream(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
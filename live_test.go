@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+	"github.com/zlm2012/wildwrap/ts"
+)
+
+func TestCueEvent(t *testing.T) {
+	extractor := ts.NewCaptionExtractor(bytes.NewReader(nil))
+	red := b24.DefaultStyle
+	red.Foreground = 1
+	cue := ts.CaptionCue{Start: 2 * time.Second, Runs: []b24.Run{
+		{Style: b24.DefaultStyle, Text: "前"},
+		{Style: red, Position: b24.Position{Row: 7, Column: 4}, HasPosition: true, Text: "\fあい"},
+	}}
+	event := cueEvent(extractor, cue)
+	if event.Type != "cue" || event.Language != "lang0" || event.PTS != 2*90000 || event.Offset != "2s" || event.Time != "" {
+		t.Fatalf("unexpected event: %+v", event)
+	}
+	if !event.Clear || event.Text != "あい" || len(event.Runs) != 1 {
+		t.Fatalf("unexpected text of event: %+v", event)
+	}
+	run := event.Runs[0]
+	if run.Text != "あい" || run.Foreground != "#FF0000" || run.Size != "normal" || run.Row == nil || *run.Row != 7 || *run.Column != 4 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+}
+
+func TestOpenLiveInput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/stream" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte("ts"))
+	}))
+	defer server.Close()
+
+	input, err := openLiveInput(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer input.Close()
+	if body, err := io.ReadAll(input); err != nil || string(body) != "ts" {
+		t.Fatalf("unexpected body: %q %v", body, err)
+	}
+	if _, err := openLiveInput(server.URL + "/missing"); err == nil {
+		t.Fatal("not found should fail")
+	}
+}
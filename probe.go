@@ -0,0 +1,49 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/zlm2012/wildwrap/ts"
+)
+
+// runProbe prints the summary of a TS file as one JSON document
+func runProbe(args []string) {
+	flags := flag.NewFlagSet("probe", flag.ExitOnError)
+	head := flags.Int64("head", ts.DefaultProbeHeadSize>>20, "MB to read from the beginning")
+	tail := flags.Int64("tail", ts.DefaultProbeTailSize>>20, "MB to read from the end")
+	verbose := flags.Bool("v", false, "log what the decoder reports while reading")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s probe [options] <file.ts>\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	file, err := os.Open(flags.Arg(0))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	defer file.Close()
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+	result, err := ts.Probe(file, *head<<20, *tail<<20)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	encoder := json.NewEncoder(os.Stdout)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(result); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
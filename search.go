@@ -0,0 +1,83 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/zlm2012/wildwrap/index"
+)
+
+// defaultIndexPath is the caption index file used unless -db is given
+const defaultIndexPath = "captions.idx"
+
+// runIndex adds the captions of the recordings to the index
+func runIndex(args []string) {
+	flags := flag.NewFlagSet("index", flag.ExitOnError)
+	db := flags.String("db", defaultIndexPath, "caption index file")
+	serviceID := flags.Uint("service", 0, "service ID, or the first service with captions if 0")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s index [options] <file.ts>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	ix, err := index.Open(*db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, path := range flags.Args() {
+		if abs, err := filepath.Abs(path); err == nil {
+			path = abs
+		}
+		entries, err := index.ExtractEntries(path, uint16(*serviceID))
+		if err != nil {
+			log.Printf("%s: %v", path, err)
+			continue
+		}
+		ix.Add(path, entries)
+		log.Printf("%s: %d cues", path, len(entries))
+	}
+	if err := ix.Save(); err != nil {
+		log.Fatalln(err)
+	}
+}
+
+// runSearch prints the recordings and the offsets of the cues matching the query
+func runSearch(args []string) {
+	flags := flag.NewFlagSet("search", flag.ExitOnError)
+	db := flags.String("db", defaultIndexPath, "caption index file")
+	serviceID := flags.Uint("service", 0, "only cues of the service ID if not 0")
+	eventID := flags.Uint("event", 0, "only cues of the event ID if not 0")
+	flags.Usage = func() {
+		fmt.Fprintf(flags.Output(), "usage: %s search [options] <words>...\n", os.Args[0])
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() == 0 {
+		flags.Usage()
+		os.Exit(2)
+	}
+	ix, err := index.Open(*db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	for _, entry := range ix.Search(strings.Join(flags.Args(), " ")) {
+		if (*serviceID != 0 && uint(entry.ServiceID) != *serviceID) || (*eventID != 0 && uint(entry.EventID) != *eventID) {
+			continue
+		}
+		wallClock := "-"
+		if !entry.Time.IsZero() {
+			wallClock = entry.Time.Format(time.RFC3339)
+		}
+		text := strings.ReplaceAll(entry.Text, "\n", " ")
+		fmt.Printf("%s\t%s\t%s\t%d\t%s\t%s\n", entry.Recording, index.FormatOffset(entry.Offset), wallClock, entry.ServiceID, entry.EventName, text)
+	}
+}
@@ -0,0 +1,359 @@
+package ts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"github.com/zlm2012/wildwrap/b24"
+	"io"
+	"sort"
+	"time"
+)
+
+type EITScheduleCycleGroup struct {
+	NumOfSegment int           `json:"num_of_segment"`
+	Cycle        time.Duration `json:"cycle"`
+}
+
+type EITScheduleParameter struct {
+	MediaType     uint8                   `json:"media_type"`     // terrestrial only
+	Pattern       uint8                   `json:"pattern"`        // terrestrial only
+	ScheduleRange int                     `json:"schedule_range"` // in days
+	BaseCycle     time.Duration           `json:"base_cycle"`
+	CycleGroups   []EITScheduleCycleGroup `json:"cycle_groups"`
+}
+
+// MaxCycle is the longest cycle for the whole schedule to be transmitted once
+func (p *EITScheduleParameter) MaxCycle() time.Duration {
+	result := p.BaseCycle
+	for _, group := range p.CycleGroups {
+		if group.Cycle > result {
+			result = group.Cycle
+		}
+	}
+	return result
+}
+
+type SIParameterEntry struct {
+	TableID          TableID               `json:"table_id"`
+	TableCycle       time.Duration         `json:"table_cycle"` // first cycle field for tables other than EIT schedule, the rest is left in TableDescription
+	Schedule         *EITScheduleParameter `json:"schedule"`
+	TableDescription []byte                `json:"table_description"`
+}
+
+type SIParameterDescriptor struct {
+	ParameterVersion uint8              `json:"parameter_version"`
+	UpdateTime       time.Time          `json:"update_time"`
+	Entries          []SIParameterEntry `json:"entries"`
+}
+
+type BITBroadcaster struct {
+	BroadcasterID   uint8                   `json:"broadcaster_id"`
+	BroadcasterName string                  `json:"broadcaster_name"`
+	ServiceList     map[uint16]ServiceType  `json:"service_list"`
+	SIParameters    []SIParameterDescriptor `json:"si_parameters"`
+	// UnknownDescriptors are the descriptors of the broadcaster not parsed
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
+}
+
+type BITFrame struct {
+	TableID                TableID                 `json:"table_id"`
+	OriginalNetworkID      uint16                  `json:"original_network_id"`
+	Version                uint8                   `json:"version"`
+	CurrentNext            bool                    `json:"current_next"`
+	Section                uint8                   `json:"section"`
+	LastSection            uint8                   `json:"last_section"`
+	BroadcastViewPropriety bool                    `json:"broadcast_view_propriety"`
+	SIParameters           []SIParameterDescriptor `json:"si_parameters"`
+	Broadcasters           []BITBroadcaster        `json:"broadcasters"`
+	// UnknownDescriptors are the first loop descriptors not parsed
+	UnknownDescriptors []Descriptor  `json:"unknown_descriptors"`
+	TextWarnings       []b24.Warning `json:"text_warnings"`
+}
+
+func (f *BITFrame) IsParsed() bool {
+	return true
+}
+
+func (f *BITFrame) GetType() string {
+	return "BIT"
+}
+
+// EITScheduleCycle returns the longest EIT schedule cycle announced in the frame, or 0 if not announced
+func (f *BITFrame) EITScheduleCycle() time.Duration {
+	result := time.Duration(0)
+	descs := append([]SIParameterDescriptor{}, f.SIParameters...)
+	for _, broadcaster := range f.Broadcasters {
+		descs = append(descs, broadcaster.SIParameters...)
+	}
+	for _, desc := range descs {
+		for _, entry := range desc.Entries {
+			if entry.Schedule != nil && entry.Schedule.MaxCycle() > result {
+				result = entry.Schedule.MaxCycle()
+			}
+		}
+	}
+	return result
+}
+
+func isEITScheduleTID(tableID TableID) bool {
+	return tableID&0xf0 == EITCurrentSchedTIDMask || tableID&0xf0 == EITOtherSchedTIDMask
+}
+
+func parseBCDDuration(raw []byte, digits int) time.Duration {
+	return time.Duration(parseBCD(raw, digits)) * time.Second
+}
+
+// parseSIParameterDescriptor parses the descriptor of at least 3 bytes, leaving out the truncated entry at the end
+func parseSIParameterDescriptor(tagContent []byte, terrestrial bool) SIParameterDescriptor {
+	desc := SIParameterDescriptor{}
+	desc.ParameterVersion = tagContent[0]
+	desc.UpdateTime = parseMjd(append(append([]byte{}, tagContent[1:3]...), 0, 0, 0))
+	tagContent = tagContent[3:]
+	for len(tagContent) >= 2 {
+		entry := SIParameterEntry{}
+		entry.TableID = TableID(tagContent[0])
+		descLen := int(tagContent[1])
+		if 2+descLen > len(tagContent) {
+			// truncated
+			break
+		}
+		entry.TableDescription = tagContent[2 : 2+descLen]
+		tagContent = tagContent[2+descLen:]
+		raw := entry.TableDescription
+		if isEITScheduleTID(entry.TableID) {
+			schedule := EITScheduleParameter{}
+			if terrestrial && len(raw) > 0 {
+				schedule.MediaType = raw[0] >> 6
+				schedule.Pattern = raw[0] >> 4 & 0b11
+				raw = raw[1:]
+			}
+			if len(raw) >= 3 {
+				schedule.ScheduleRange = int(parseBCD(raw[0:1], 2))
+				schedule.BaseCycle = parseBCDDuration(raw[1:3], 3)
+				groupCount := int(raw[2] & 0b11)
+				raw = raw[3:]
+				for i := 0; i < groupCount && len(raw) >= 2; i++ {
+					schedule.CycleGroups = append(schedule.CycleGroups, EITScheduleCycleGroup{int(parseBCD(raw[0:1], 2)), parseBCDDuration(raw[1:2], 2)})
+					raw = raw[2:]
+				}
+			}
+			entry.Schedule = &schedule
+		} else if len(raw) > 0 {
+			entry.TableCycle = parseBCDDuration(raw[0:1], 2)
+		}
+		desc.Entries = append(desc.Entries, entry)
+	}
+	return desc
+}
+
+func parseBIT(payload []byte, _ *Decoder) (Frame, error) {
+	if len(payload) < 14 || TableID(payload[0]) != BITTID || payload[1]&0xf0 != 0xf0 {
+		return nil, errors.New("illegal BIT frame")
+	}
+	frame := BITFrame{}
+	text := textDecoder{}
+	frame.TableID = TableID(payload[0])
+	frame.OriginalNetworkID = binary.BigEndian.Uint16(payload[3:5])
+	frame.Version = payload[5] & 0b111110 >> 1
+	frame.CurrentNext = payload[5]&1 == 1
+	frame.Section = payload[6]
+	frame.LastSection = payload[7]
+	frame.BroadcastViewPropriety = payload[8]&0x10 == 0x10
+	frame.Broadcasters = make([]BITBroadcaster, 0)
+	terrestrial := ChannelTypeOf(frame.OriginalNetworkID) == ChannelTypeGR
+	firstDescLen := binary.BigEndian.Uint16(payload[8:10]) & 0xfff
+	if int(firstDescLen)+14 > len(payload) {
+		return nil, errors.New("illegal BIT first descriptors length")
+	}
+	firstDescReader := bytes.NewReader(payload[10 : 10+firstDescLen])
+	for {
+		tagID, tagContent, err := extractDescriptor(firstDescReader)
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				return nil, err
+			}
+		}
+		// descriptors too short to be parsed are left as unknown ones
+		switch {
+		case tagID == SIParameterDescTagID && len(tagContent) >= 3:
+			frame.SIParameters = append(frame.SIParameters, parseSIParameterDescriptor(tagContent, terrestrial))
+		default:
+			frame.UnknownDescriptors = append(frame.UnknownDescriptors, Descriptor{tagID, tagContent})
+		}
+	}
+	payload = payload[10+firstDescLen : len(payload)-4]
+	for len(payload) > 0 {
+		if len(payload) < 3 {
+			return nil, errors.New("illegal BIT broadcaster loop")
+		}
+		broadcaster := BITBroadcaster{}
+		broadcaster.BroadcasterID = payload[0]
+		broadcaster.ServiceList = make(map[uint16]ServiceType)
+		descLen := binary.BigEndian.Uint16(payload[1:3]) & 0xfff
+		if int(descLen)+3 > len(payload) {
+			return nil, errors.New("illegal BIT broadcaster descriptors length")
+		}
+		descReader := bytes.NewReader(payload[3 : 3+descLen])
+		payload = payload[3+descLen:]
+		for {
+			tagID, tagContent, err := extractDescriptor(descReader)
+			if err != nil {
+				if err == io.EOF {
+					break
+				} else {
+					return nil, err
+				}
+			}
+			switch {
+			case tagID == BroadcasterNameDescTagID:
+				broadcaster.BroadcasterName = text.decode(tagContent)
+			case tagID == ServiceListDescTagID:
+				for len(tagContent) >= 3 {
+					broadcaster.ServiceList[binary.BigEndian.Uint16(tagContent[0:2])] = ServiceType(tagContent[2])
+					tagContent = tagContent[3:]
+				}
+			case tagID == SIParameterDescTagID && len(tagContent) >= 3:
+				broadcaster.SIParameters = append(broadcaster.SIParameters, parseSIParameterDescriptor(tagContent, terrestrial))
+			case tagID == ExtendedBroadcasterDescTagID:
+				// ignore
+			default:
+				broadcaster.UnknownDescriptors = append(broadcaster.UnknownDescriptors, Descriptor{tagID, tagContent})
+			}
+		}
+		frame.Broadcasters = append(frame.Broadcasters, broadcaster)
+	}
+	frame.TextWarnings = text.warnings
+	return &frame, nil
+}
+
+type BroadcasterInfo struct {
+	OriginalNetworkID uint16
+	BroadcasterID     uint8
+	BroadcasterName   string
+	Services          []ServiceInfo
+}
+
+type broadcasterKey struct {
+	onid          uint16
+	broadcasterID uint8
+}
+
+// BroadcasterDirectory joins BIT with the service directory to give services of each broadcaster
+type BroadcasterDirectory struct {
+	versions       map[uint16]uint8
+	broadcasters   map[broadcasterKey]*BITBroadcaster
+	scheduleCycles map[uint16]time.Duration
+	services       *ServiceDirectory
+}
+
+func NewBroadcasterDirectory(services *ServiceDirectory) *BroadcasterDirectory {
+	return &BroadcasterDirectory{
+		make(map[uint16]uint8),
+		make(map[broadcasterKey]*BITBroadcaster),
+		make(map[uint16]time.Duration),
+		services,
+	}
+}
+
+// Update applies BIT frames, and NIT and SDT frames to the underlying service directory
+func (d *BroadcasterDirectory) Update(frame Frame) bool {
+	bit, ok := frame.(*BITFrame)
+	if !ok {
+		return d.services.Update(frame)
+	}
+	if !bit.CurrentNext {
+		return false
+	}
+	if version, ok := d.versions[bit.OriginalNetworkID]; !ok || version != bit.Version {
+		for key := range d.broadcasters {
+			if key.onid == bit.OriginalNetworkID {
+				delete(d.broadcasters, key)
+			}
+		}
+		d.versions[bit.OriginalNetworkID] = bit.Version
+	}
+	for i := range bit.Broadcasters {
+		broadcaster := bit.Broadcasters[i]
+		d.broadcasters[broadcasterKey{bit.OriginalNetworkID, broadcaster.BroadcasterID}] = &broadcaster
+	}
+	if cycle := bit.EITScheduleCycle(); cycle > 0 {
+		d.scheduleCycles[bit.OriginalNetworkID] = cycle
+	}
+	return true
+}
+
+// EITScheduleCycle tells how long an EPG collector should wait to receive the whole EIT schedule of the network
+func (d *BroadcasterDirectory) EITScheduleCycle(onid uint16) time.Duration {
+	return d.scheduleCycles[onid]
+}
+
+func (d *BroadcasterDirectory) info(key broadcasterKey) *BroadcasterInfo {
+	broadcaster := d.broadcasters[key]
+	info := BroadcasterInfo{key.onid, key.broadcasterID, broadcaster.BroadcasterName, make([]ServiceInfo, 0)}
+	if len(broadcaster.ServiceList) > 0 {
+		sids := make([]uint16, 0, len(broadcaster.ServiceList))
+		for sid := range broadcaster.ServiceList {
+			sids = append(sids, sid)
+		}
+		sort.Slice(sids, func(i, j int) bool { return sids[i] < sids[j] })
+		for _, sid := range sids {
+			if service := d.services.FindService(key.onid, sid); service != nil {
+				info.Services = append(info.Services, *service)
+			}
+		}
+	} else if d.broadcasterCount(key.onid) == 1 {
+		// the only broadcaster in the network owns all the services, e.g. terrestrial
+		for _, service := range d.services.Services() {
+			if service.OriginalNetworkID == key.onid {
+				info.Services = append(info.Services, service)
+			}
+		}
+	}
+	return &info
+}
+
+func (d *BroadcasterDirectory) broadcasterCount(onid uint16) int {
+	count := 0
+	for key := range d.broadcasters {
+		if key.onid == onid {
+			count++
+		}
+	}
+	return count
+}
+
+func (d *BroadcasterDirectory) Broadcasters() []*BroadcasterInfo {
+	keys := make([]broadcasterKey, 0, len(d.broadcasters))
+	for key := range d.broadcasters {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].onid != keys[j].onid {
+			return keys[i].onid < keys[j].onid
+		}
+		return keys[i].broadcasterID < keys[j].broadcasterID
+	})
+	result := make([]*BroadcasterInfo, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, d.info(key))
+	}
+	return result
+}
+
+// BroadcasterOf returns the broadcaster which the service belongs to, or nil if not found
+func (d *BroadcasterDirectory) BroadcasterOf(onid uint16, sid uint16) *BroadcasterInfo {
+	for _, info := range d.Broadcasters() {
+		if info.OriginalNetworkID != onid {
+			continue
+		}
+		for _, service := range info.Services {
+			if service.ServiceID == sid {
+				return info
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package ts
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+func TestParseSIParameterDescriptor(t *testing.T) {
+	// version 1, update 2022-03-01, NIT cycle 10s, EIT schedule 8 days with base cycle 120s and one group of 8 segments per 30s
+	desc := parseSIParameterDescriptor([]byte{0x01, 0xE8, 0xF7, 0x40, 0x01, 0x10, 0x50, 0x05, 0x08, 0x12, 0x01, 0x08, 0x30}, false)
+	if desc.ParameterVersion != 1 || desc.UpdateTime.Format("2006-01-02") != "2022-03-01" || len(desc.Entries) != 2 {
+		t.Fatalf("unexpected descriptor: %+v", desc)
+	}
+	if desc.Entries[0].TableCycle != 10*time.Second {
+		t.Fatalf("unexpected NIT cycle: %v", desc.Entries[0].TableCycle)
+	}
+	schedule := desc.Entries[1].Schedule
+	if schedule == nil || schedule.ScheduleRange != 8 || schedule.BaseCycle != 120*time.Second || len(schedule.CycleGroups) != 1 {
+		t.Fatalf("unexpected schedule: %+v", schedule)
+	}
+	if schedule.CycleGroups[0].NumOfSegment != 8 || schedule.MaxCycle() != 120*time.Second {
+		t.Fatalf("unexpected cycle group: %+v", schedule.CycleGroups[0])
+	}
+}
+
+func TestParseSIParameterDescriptorEITPF(t *testing.T) {
+	// terrestrial EIT p/f: H-EIT 2s, M-EIT 10s, L-EIT 30s, 1 M-EIT event and 2 L-EIT events
+	desc := parseSIParameterDescriptor([]byte{0x01, 0xE8, 0xF7, 0x4E, 0x04, 0x02, 0x10, 0x30, 0x12}, true)
+	if len(desc.Entries) != 1 || desc.Entries[0].Schedule != nil || desc.Entries[0].TableCycle != 2*time.Second {
+		t.Fatalf("unexpected descriptor: %+v", desc)
+	}
+	if len(desc.Entries[0].TableDescription) != 4 {
+		t.Fatalf("unexpected table description: %x", desc.Entries[0].TableDescription)
+	}
+}
+
+func TestParseSIParameterDescriptorTruncated(t *testing.T) {
+	desc := parseSIParameterDescriptor([]byte{0x01, 0xE8, 0xF7, 0x40, 0x01, 0x10, 0x50, 0x05, 0x08}, false)
+	if len(desc.Entries) != 1 || desc.Entries[0].TableCycle != 10*time.Second {
+		t.Fatalf("unexpected descriptor: %+v", desc)
+	}
+}
+
+func TestBroadcasterDirectory(t *testing.T) {
+	broadcasterDesc := func(id uint8, name string, services ...byte) []byte {
+		encoded, err := b24.EncodeString(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		desc := append([]byte{BroadcasterNameDescTagID, byte(len(encoded))}, encoded...)
+		desc = append(desc, ServiceListDescTagID, byte(len(services)))
+		desc = append(desc, services...)
+		return append([]byte{id, 0xf0, byte(len(desc))}, desc...)
+	}
+	firstDesc := []byte{SIParameterDescTagID, 13, 0x01, 0xE8, 0xF7, 0x40, 0x01, 0x10, 0x50, 0x05, 0x08, 0x12, 0x01, 0x08, 0x30,
+		// truncated
+		SIParameterDescTagID, 2, 0x01, 0xE8}
+	bit := []byte{byte(BITTID), 0xf0, 0, 0x00, 0x04, 0xc3, 0, 0, 0xf0, byte(len(firstDesc))}
+	bit = append(bit, firstDesc...)
+	bit = append(bit, broadcasterDesc(1, "NHK", 0x00, 0x65, 0x01, 0x00, 0x66, 0x01)...)
+	bit = append(bit, broadcasterDesc(2, "BS朝日", 0x00, 0x97, 0x01, 0x00)...)
+	bit = append(bit, 0, 0, 0, 0)
+	frame, err := parseBIT(bit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bitFrame := frame.(*BITFrame)
+	if bitFrame.OriginalNetworkID != 4 || bitFrame.Version != 1 || len(bitFrame.SIParameters) != 1 || len(bitFrame.UnknownDescriptors) != 1 ||
+		len(bitFrame.Broadcasters) != 2 || bitFrame.Broadcasters[1].BroadcasterName != "BS朝日" || len(bitFrame.Broadcasters[1].ServiceList) != 1 {
+		t.Fatalf("unexpected BIT: %+v", bitFrame)
+	}
+
+	directory := NewBroadcasterDirectory(NewServiceDirectory())
+	directory.Update(&NITFrame{TableID: NITActualTID, NetworkID: 4, CurrentNext: true, TransportStreams: []NITTransportEntry{
+		{TransportStreamId: 0x4010, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01, 102: 0x01}},
+		{TransportStreamId: 0x4011, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{151: 0x01}},
+	}})
+	directory.Update(&SDTFrame{TableID: SDTOtherTID, TransportStreamID: 0x4011, OriginalNetworkID: 4, CurrentNext: true,
+		Entries: []SDTFrameEntry{{ServiceID: 151, Service: ServiceDescriptor{0x01, "BS朝日", "BS朝日1"}}}})
+	if !directory.Update(bitFrame) {
+		t.Fatal("BIT should change the directory")
+	}
+	broadcasters := directory.Broadcasters()
+	if len(broadcasters) != 2 || broadcasters[0].BroadcasterName != "NHK" || len(broadcasters[0].Services) != 2 || broadcasters[0].Services[1].ServiceID != 102 {
+		t.Fatalf("unexpected broadcasters: %+v", broadcasters)
+	}
+	if len(broadcasters[1].Services) != 1 || broadcasters[1].Services[0].ServiceName != "BS朝日1" || broadcasters[1].Services[0].TransportStreamID != 0x4011 {
+		t.Fatalf("unexpected broadcaster: %+v", broadcasters[1])
+	}
+	if directory.EITScheduleCycle(4) != 120*time.Second {
+		t.Fatalf("unexpected EIT schedule cycle: %v", directory.EITScheduleCycle(4))
+	}
+
+	updated := *bitFrame
+	updated.Version = 2
+	updated.Broadcasters = updated.Broadcasters[:1]
+	if !directory.Update(&updated) || len(directory.Broadcasters()) != 1 {
+		t.Fatalf("broadcaster dropped by new BIT version should be removed: %+v", directory.Broadcasters())
+	}
+}
@@ -0,0 +1,261 @@
+package ts
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+const (
+	CaptionDataIdentifier     uint8 = 0x80
+	SuperimposeDataIdentifier uint8 = 0x81
+
+	DataUnitStatementBody uint8 = 0x20
+	DataUnitDRCS1Byte     uint8 = 0x30
+	DataUnitDRCS2Byte     uint8 = 0x31
+)
+
+// TMD is the time control mode of caption data
+type TMD uint8
+
+const (
+	TMDFree TMD = iota
+	TMDRealTime
+	TMDOffsetTime
+)
+
+// CaptionDataUnit is a data unit in caption management or statement data
+type CaptionDataUnit struct {
+	Parameter uint8
+	Data      []byte
+}
+
+// CaptionLanguage is a language listed in the caption management data
+type CaptionLanguage struct {
+	// Tag is 0-7, the caption statement of the language is in data group 1-8
+	Tag uint8
+	// DMF is the display mode, on receiving and on recording playback
+	DMF uint8
+	// DC is the display condition, given only for some DMF
+	DC       uint8
+	LangCode string
+	// Format is the display format, e.g. 0b1000 for 960x540 horizontal
+	Format     uint8
+	TCS        uint8
+	RollupMode uint8
+}
+
+// DisplayMode is half of DMF, whether captions are displayed on receiving or on recording playback
+type DisplayMode uint8
+
+const (
+	DisplayAuto DisplayMode = iota
+	DisplayAutoOff
+	DisplaySelectable
+	DisplaySpecialCondition
+)
+
+func (m DisplayMode) String() string {
+	switch m {
+	case DisplayAuto:
+		return "auto"
+	case DisplayAutoOff:
+		return "auto off"
+	case DisplaySelectable:
+		return "selectable"
+	case DisplaySpecialCondition:
+		return "special condition"
+	}
+	return fmt.Sprintf("DisplayMode(%d)", uint8(m))
+}
+
+// ReceptionDisplayMode gives how the language is displayed on receiving
+func (l *CaptionLanguage) ReceptionDisplayMode() DisplayMode {
+	return DisplayMode(l.DMF >> 2)
+}
+
+// PlaybackDisplayMode gives how the language is displayed on recording playback
+func (l *CaptionLanguage) PlaybackDisplayMode() DisplayMode {
+	return DisplayMode(l.DMF & 0b11)
+}
+
+// CaptionManagement is caption management data, data group 0
+type CaptionManagement struct {
+	TMD       TMD
+	OTM       uint64 // offset time in BCD, 9 digits of hhmmssmmm, valid for TMDOffsetTime
+	Languages []CaptionLanguage
+	DataUnits []CaptionDataUnit
+}
+
+// Language gives the language of the tag, or nil if it is not announced
+func (m *CaptionManagement) Language(tag uint8) *CaptionLanguage {
+	for i := range m.Languages {
+		if m.Languages[i].Tag == tag {
+			return &m.Languages[i]
+		}
+	}
+	return nil
+}
+
+// CaptionStatement is caption statement data, data group 1-8
+type CaptionStatement struct {
+	TMD       TMD
+	STM       uint64 // presentation start time in BCD, 9 digits of hhmmssmmm, valid for TMDRealTime and TMDOffsetTime
+	DataUnits []CaptionDataUnit
+}
+
+// CaptionDataGroup is a data group carried in a synchronized PES packet of caption or superimpose
+type CaptionDataGroup struct {
+	DataIdentifier uint8
+	// ID is 0x00-0x08 in group A, 0x20-0x28 in group B
+	ID             uint8
+	Version        uint8
+	LinkNumber     uint8
+	LastLinkNumber uint8
+	// either of Management and Statement is set, by ID
+	Management *CaptionManagement
+	Statement  *CaptionStatement
+}
+
+// LanguageTag gives 0-7 of the language of a statement, or -1 for management
+func (g *CaptionDataGroup) LanguageTag() int {
+	return int(g.ID&0x0f) - 1
+}
+
+// IsGroupB reports whether the data group is in group B
+func (g *CaptionDataGroup) IsGroupB() bool {
+	return g.ID&0x20 != 0
+}
+
+// ParseCaptionPES parses the payload of a synchronized PES packet of caption or superimpose
+func ParseCaptionPES(payload []byte) (*CaptionDataGroup, error) {
+	if len(payload) < 3 {
+		return nil, errors.New("caption PES too short")
+	}
+	group := CaptionDataGroup{DataIdentifier: payload[0]}
+	if group.DataIdentifier != CaptionDataIdentifier && group.DataIdentifier != SuperimposeDataIdentifier {
+		return nil, errors.New(fmt.Sprintf("unexpected data identifier %02x", group.DataIdentifier))
+	}
+	headerLen := int(payload[2] & 0x0f)
+	if len(payload) < 3+headerLen {
+		return nil, errors.New("caption PES header truncated")
+	}
+	payload = payload[3+headerLen:]
+	if len(payload) < 5 {
+		return nil, errors.New("caption data group too short")
+	}
+	group.ID = payload[0] >> 2
+	group.Version = payload[0] & 0b11
+	group.LinkNumber = payload[1]
+	group.LastLinkNumber = payload[2]
+	size := int(binary.BigEndian.Uint16(payload[3:5]))
+	if len(payload) < 5+size {
+		return nil, errors.New("caption data group truncated")
+	}
+	data := payload[5 : 5+size]
+	var err error
+	if group.ID&0x0f == 0 {
+		group.Management, err = parseCaptionManagement(data)
+	} else {
+		group.Statement, err = parseCaptionStatement(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &group, nil
+}
+
+func parseCaptionManagement(data []byte) (*CaptionManagement, error) {
+	if len(data) < 1 {
+		return nil, errors.New("caption management data too short")
+	}
+	management := CaptionManagement{TMD: TMD(data[0] >> 6)}
+	data = data[1:]
+	if management.TMD == TMDOffsetTime {
+		if len(data) < 5 {
+			return nil, errors.New("caption management data truncated at OTM")
+		}
+		management.OTM = parseBCD(data[0:5], 9)
+		data = data[5:]
+	}
+	if len(data) < 1 {
+		return nil, errors.New("caption management data truncated at languages")
+	}
+	numLanguages := int(data[0])
+	data = data[1:]
+	for i := 0; i < numLanguages; i++ {
+		if len(data) < 1 {
+			return nil, errors.New("caption management data truncated at language")
+		}
+		language := CaptionLanguage{Tag: data[0] >> 5, DMF: data[0] & 0x0f}
+		data = data[1:]
+		if language.DMF >= 0b1100 && language.DMF <= 0b1110 {
+			if len(data) < 1 {
+				return nil, errors.New("caption management data truncated at DC")
+			}
+			language.DC = data[0]
+			data = data[1:]
+		}
+		if len(data) < 4 {
+			return nil, errors.New("caption management data truncated at language code")
+		}
+		language.LangCode = string(data[0:3])
+		language.Format = data[3] >> 4
+		language.TCS = data[3] >> 2 & 0b11
+		language.RollupMode = data[3] & 0b11
+		data = data[4:]
+		management.Languages = append(management.Languages, language)
+	}
+	units, err := parseCaptionDataUnits(data)
+	if err != nil {
+		return nil, err
+	}
+	management.DataUnits = units
+	return &management, nil
+}
+
+func parseCaptionStatement(data []byte) (*CaptionStatement, error) {
+	if len(data) < 1 {
+		return nil, errors.New("caption statement data too short")
+	}
+	statement := CaptionStatement{TMD: TMD(data[0] >> 6)}
+	data = data[1:]
+	if statement.TMD == TMDRealTime || statement.TMD == TMDOffsetTime {
+		if len(data) < 5 {
+			return nil, errors.New("caption statement data truncated at STM")
+		}
+		statement.STM = parseBCD(data[0:5], 9)
+		data = data[5:]
+	}
+	units, err := parseCaptionDataUnits(data)
+	if err != nil {
+		return nil, err
+	}
+	statement.DataUnits = units
+	return &statement, nil
+}
+
+func parseCaptionDataUnits(data []byte) ([]CaptionDataUnit, error) {
+	if len(data) < 3 {
+		return nil, errors.New("caption data unit loop truncated")
+	}
+	loopLen := int(data[0])<<16 | int(data[1])<<8 | int(data[2])
+	data = data[3:]
+	if len(data) < loopLen {
+		return nil, errors.New("caption data unit loop truncated")
+	}
+	data = data[:loopLen]
+	var units []CaptionDataUnit
+	for len(data) > 0 {
+		if len(data) < 5 || data[0] != 0x1f {
+			return nil, errors.New("illegal caption data unit")
+		}
+		size := int(data[2])<<16 | int(data[3])<<8 | int(data[4])
+		if len(data) < 5+size {
+			return nil, errors.New("caption data unit truncated")
+		}
+		units = append(units, CaptionDataUnit{data[1], data[5 : 5+size]})
+		data = data[5+size:]
+	}
+	return units, nil
+}
@@ -0,0 +1,353 @@
+package ts
+
+import (
+	"errors"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+// DefaultCueDuration is the duration of the last cue of a language, which no following statement ends
+const DefaultCueDuration = 5 * time.Second
+
+// CaptionCue is a caption statement presented from Start to End, relative to the first video PTS
+type CaptionCue struct {
+	Start time.Duration
+	End   time.Duration
+	// Language is the language tag 0-7, see CaptionManagement for the language code
+	Language uint8
+	Runs     []b24.Run
+	// Format is the display format of the statement, which the positions of Runs are in
+	Format b24.DisplayFormat
+}
+
+// Text gives the plain text of the cue, which is displayed after the last CS, without the line breaks around
+func (c *CaptionCue) Text() string {
+	var sb strings.Builder
+	for _, run := range c.Runs {
+		sb.WriteString(run.Text)
+	}
+	text := sb.String()
+	if i := strings.LastIndex(text, "\f"); i >= 0 {
+		text = text[i+1:]
+	}
+	return strings.Trim(text, "\n")
+}
+
+// CaptionExtractor extracts caption cues of a program from TS, or superimpose cues if made by NewSuperimposeExtractor
+type CaptionExtractor struct {
+	// ServiceID selects the program, or the first program with a caption ES is taken if 0
+	ServiceID uint16
+	// Configure is called on the b24 decoder of each language, e.g. to set the gaiji mode or the DRCS table
+	Configure func(*b24.Decoder)
+	// Languages selects the caption languages by ISO 639 code, e.g. "jpn" and "eng", or all languages are taken if empty
+	Languages []string
+	// Management is the last caption management data
+	Management *CaptionManagement
+	// Event is the present event of the service by the last EIT p/f, or nil until it comes
+	Event *EITFrameEntry
+	// OnCue is called when a cue starts, before its end is known.
+	// For superimpose, it tells e.g. that a programme is interrupted by an emergency bulletin.
+	OnCue func(CaptionCue)
+	// OnErase is called when the cue of the language is erased by a statement without text, e.g. only CS
+	OnErase func(language uint8, at time.Duration)
+
+	decoder     *Decoder
+	superimpose bool
+	captionPID  uint16
+	videoPID    uint16
+	hasBase     bool
+	basePTS     uint64
+	lastPTS     uint64
+	pending     []*PESFrame
+	text        map[uint8]*b24.Decoder
+	cues        []*CaptionCue
+	open        map[uint8]*CaptionCue
+	languages   map[uint8]CaptionLanguage
+	live        bool
+	hasTOT      bool
+	totTime     time.Time
+	totOffset   time.Duration
+}
+
+func NewCaptionExtractor(reader io.Reader) *CaptionExtractor {
+	return &CaptionExtractor{decoder: NewDecoder(reader), text: map[uint8]*b24.Decoder{}, open: map[uint8]*CaptionCue{}, languages: map[uint8]CaptionLanguage{}}
+}
+
+// NewSuperimposeExtractor makes an extractor of superimposed text, e.g. breaking news and earthquake alerts.
+// As superimpose is carried in PES packets without PTS, the cues are timed by the video PTS at their arrival.
+func NewSuperimposeExtractor(reader io.Reader) *CaptionExtractor {
+	e := NewCaptionExtractor(reader)
+	e.superimpose = true
+	return e
+}
+
+// Extract reads TS to the end, and gives the caption cues in the order of the start time.
+// If the input fails, the cues so far are given with the ReadError.
+func (e *CaptionExtractor) Extract() ([]CaptionCue, error) {
+	err := e.run()
+	e.closeAll()
+	return e.selectedCues(), err
+}
+
+// Stream reads TS to the end like Extract, but gives the cues only to OnCue and OnErase without keeping them.
+// It is for a live TS which does not end, and returns the ReadError when the input fails, e.g. the connection is reset.
+func (e *CaptionExtractor) Stream() error {
+	e.live = true
+	return e.run()
+}
+
+// run handles the frames to the end of the input, skipping broken packets and sections
+func (e *CaptionExtractor) run() error {
+	for {
+		frame, err := e.decoder.ParseNext()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		var readErr *ReadError
+		if errors.As(err, &readErr) {
+			return err
+		}
+		if err != nil {
+			log.Printf("caption extractor: %v", err)
+			continue
+		}
+		e.handleFrame(frame)
+	}
+}
+
+// PTSAt gives the PTS of the time relative to the first video PTS
+func (e *CaptionExtractor) PTSAt(at time.Duration) uint64 {
+	return (e.basePTS + uint64(at*time.Duration(PTSClock)/time.Second)) & ptsMask
+}
+
+// WallClockAt gives the wall-clock time of the time relative to the first video PTS, estimated by the last TOT.
+// false is returned until a TOT comes.
+func (e *CaptionExtractor) WallClockAt(at time.Duration) (time.Time, bool) {
+	if !e.hasTOT {
+		return time.Time{}, false
+	}
+	return e.totTime.Add(at - e.totOffset), true
+}
+
+// CaptionLanguages gives the languages announced by caption management data so far, in the order of tags
+func (e *CaptionExtractor) CaptionLanguages() []CaptionLanguage {
+	var languages []CaptionLanguage
+	for tag := uint8(0); tag < 8; tag++ {
+		if language, ok := e.languages[tag]; ok {
+			languages = append(languages, language)
+		}
+	}
+	return languages
+}
+
+// LanguageCode gives the ISO 639 code of the language tag, or "" if it is not announced
+func (e *CaptionExtractor) LanguageCode(tag uint8) string {
+	return e.languages[tag].LangCode
+}
+
+// selectedCues gives the cues of the languages selected by Languages
+func (e *CaptionExtractor) selectedCues() []CaptionCue {
+	var cues []CaptionCue
+	for _, cue := range e.cues {
+		if len(e.Languages) == 0 {
+			cues = append(cues, *cue)
+			continue
+		}
+		code := e.LanguageCode(cue.Language)
+		for _, selected := range e.Languages {
+			if code == selected {
+				cues = append(cues, *cue)
+				break
+			}
+		}
+	}
+	return cues
+}
+
+func (e *CaptionExtractor) handleFrame(frame Frame) {
+	switch f := frame.(type) {
+	case *TOTFrame:
+		if e.hasBase {
+			e.hasTOT = true
+			e.totTime = f.JSTTime
+			e.totOffset = e.offset(e.lastPTS)
+		}
+	case *EITFrame:
+		if f.TableID == EITCurrentStreamTID && f.SectionNumber == 0 && f.ServiceID == e.ServiceID && len(f.Entries) > 0 {
+			e.Event = &f.Entries[0]
+		}
+	case *PMTFrame:
+		e.selectStreams(f)
+	case *PESFrame:
+		if f.PID == e.videoPID {
+			if !f.HasPTS {
+				return
+			}
+			if !e.hasBase {
+				e.setBase(f.PTS)
+			}
+			e.updateLastPTS(f.PTS)
+		} else if f.PID == e.captionPID {
+			if !e.hasBase {
+				if e.videoPID != 0 {
+					e.pending = append(e.pending, f)
+					return
+				}
+				if !f.HasPTS {
+					return
+				}
+				e.setBase(f.PTS)
+			}
+			e.handleCaption(f, e.ptsOf(f))
+		}
+	}
+}
+
+func (e *CaptionExtractor) selectStreams(pmt *PMTFrame) {
+	if e.captionPID != 0 || (e.ServiceID != 0 && pmt.ServiceID != e.ServiceID) {
+		return
+	}
+	captions := pmt.CaptionStreams()
+	if e.superimpose {
+		captions = pmt.SuperimposeStreams()
+	}
+	if len(captions) == 0 {
+		return
+	}
+	e.ServiceID = pmt.ServiceID
+	e.captionPID = captions[0].PID
+	e.decoder.WatchPES(e.captionPID, false)
+	if video := pmt.PrimaryVideo(); video != nil {
+		e.videoPID = video.PID
+		e.decoder.WatchPES(e.videoPID, true)
+	}
+}
+
+func (e *CaptionExtractor) setBase(pts uint64) {
+	e.hasBase = true
+	e.basePTS = pts
+	e.lastPTS = pts
+	pending := e.pending
+	e.pending = nil
+	for _, f := range pending {
+		e.handleCaption(f, e.ptsOf(f))
+	}
+}
+
+// ptsOf gives the PTS of the caption PES, or the last PTS for PES without PTS like superimpose
+func (e *CaptionExtractor) ptsOf(f *PESFrame) uint64 {
+	if f.HasPTS {
+		return f.PTS
+	}
+	return e.lastPTS
+}
+
+func (e *CaptionExtractor) updateLastPTS(pts uint64) {
+	if PTSDiff(pts, e.lastPTS) > 0 {
+		e.lastPTS = pts
+	}
+}
+
+// offset gives the time of the PTS from the base
+func (e *CaptionExtractor) offset(pts uint64) time.Duration {
+	diff := PTSDiff(pts, e.basePTS)
+	if diff < 0 {
+		diff = 0
+	}
+	return time.Duration(diff) * time.Second / time.Duration(PTSClock)
+}
+
+func (e *CaptionExtractor) handleCaption(f *PESFrame, pts uint64) {
+	e.updateLastPTS(pts)
+	group, err := ParseCaptionPES(f.Payload)
+	if err != nil {
+		log.Printf("caption extractor: %v", err)
+		return
+	}
+	if group.Management != nil {
+		e.Management = group.Management
+		for _, language := range group.Management.Languages {
+			e.languages[language.Tag] = language
+		}
+		return
+	}
+	e.addStatement(uint8(group.LanguageTag()), group.Statement, e.offset(pts))
+}
+
+// textDecoder gives the b24 decoder of the language
+func (e *CaptionExtractor) textDecoder(language uint8) *b24.Decoder {
+	decoder, ok := e.text[language]
+	if !ok {
+		decoder = b24.NewDecoder(b24.CaptionInitialState)
+		decoder.Lenient = true
+		if e.Configure != nil {
+			e.Configure(decoder)
+		}
+		e.text[language] = decoder
+	}
+	return decoder
+}
+
+// addStatement ends the cue being presented in the language, and starts a new one unless the statement only erases
+func (e *CaptionExtractor) addStatement(language uint8, statement *CaptionStatement, at time.Duration) {
+	decoder := e.textDecoder(language)
+	decoder.Reset()
+	cue := CaptionCue{Start: at, Language: language}
+	for _, unit := range statement.DataUnits {
+		switch unit.Parameter {
+		case DataUnitStatementBody:
+			runs, err := decoder.DecodeStyled(unit.Data)
+			if err != nil {
+				log.Printf("caption extractor: %v", err)
+				continue
+			}
+			cue.Runs = append(cue.Runs, runs...)
+		case DataUnitDRCS1Byte, DataUnitDRCS2Byte:
+			if err := decoder.LoadDRCS(unit.Data); err != nil {
+				log.Printf("caption extractor: %v", err)
+			}
+		}
+	}
+	cue.Format = decoder.Format()
+	erased := e.closeCue(language, at)
+	if cue.Text() == "" {
+		if erased && e.OnErase != nil {
+			e.OnErase(language, at)
+		}
+		return
+	}
+	e.open[language] = &cue
+	if !e.live {
+		e.cues = append(e.cues, &cue)
+	}
+	if e.OnCue != nil {
+		e.OnCue(cue)
+	}
+}
+
+// closeCue ends the cue being presented in the language, and reports whether there is one
+func (e *CaptionExtractor) closeCue(language uint8, at time.Duration) bool {
+	cue, ok := e.open[language]
+	if ok {
+		cue.End = at
+		delete(e.open, language)
+	}
+	return ok
+}
+
+// closeAll ends the cues still presented at the end of TS
+func (e *CaptionExtractor) closeAll() {
+	end := e.offset(e.lastPTS)
+	for language, cue := range e.open {
+		if end > cue.Start {
+			cue.End = end
+		} else {
+			cue.End = cue.Start + DefaultCueDuration
+		}
+		delete(e.open, language)
+	}
+}
@@ -0,0 +1,280 @@
+package ts
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+// captionPES builds the payload of a caption PES packet with a data group of the data units
+func captionPES(groupID uint8, groupData []byte) []byte {
+	pes := []byte{CaptionDataIdentifier, 0xff, 0xf0, groupID << 2, 0, 0, byte(len(groupData) >> 8), byte(len(groupData))}
+	pes = append(pes, groupData...)
+	return append(pes, 0, 0) // CRC16
+}
+
+func captionDataUnits(units ...CaptionDataUnit) []byte {
+	var loop []byte
+	for _, unit := range units {
+		loop = append(loop, 0x1f, unit.Parameter, byte(len(unit.Data)>>16), byte(len(unit.Data)>>8), byte(len(unit.Data)))
+		loop = append(loop, unit.Data...)
+	}
+	return append([]byte{byte(len(loop) >> 16), byte(len(loop) >> 8), byte(len(loop))}, loop...)
+}
+
+func captionStatementPES(language uint8, body []byte) []byte {
+	return captionPES(language+1, append([]byte{0x00}, captionDataUnits(CaptionDataUnit{DataUnitStatementBody, body})...))
+}
+
+func TestParseCaptionPES(t *testing.T) {
+	management := append([]byte{0x00, 0x01, 0x00, 'j', 'p', 'n', 0x80}, captionDataUnits()...)
+	group, err := ParseCaptionPES(captionPES(0x20, management))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Management == nil || !group.IsGroupB() || group.LanguageTag() != -1 {
+		t.Fatalf("unexpected data group: %+v", group)
+	}
+	if len(group.Management.Languages) != 1 || group.Management.Languages[0].LangCode != "jpn" || group.Management.Languages[0].Format != 0b1000 {
+		t.Fatalf("unexpected languages: %+v", group.Management.Languages)
+	}
+
+	group, err = ParseCaptionPES(captionStatementPES(0, []byte{0xa2}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.Statement == nil || group.LanguageTag() != 0 || len(group.Statement.DataUnits) != 1 || group.Statement.DataUnits[0].Parameter != DataUnitStatementBody {
+		t.Fatalf("unexpected data group: %+v", group)
+	}
+	if _, err := ParseCaptionPES(captionStatementPES(0, []byte{0xa2})[:8]); err == nil {
+		t.Fatal("truncated data group should fail")
+	}
+	for _, payload := range [][]byte{{0x80, 0xff, 0xff}, {0x80, 0xff, 0xf5, 0x00, 0x00}} {
+		if _, err := ParseCaptionPES(payload); err == nil {
+			t.Fatalf("truncated PES header should fail: %02x", payload)
+		}
+	}
+}
+
+func TestSubtitleBlankLines(t *testing.T) {
+	// CS followed by APR pairs leaves empty lines in the text
+	cues := []CaptionCue{{Start: time.Second, End: 2 * time.Second, Runs: []b24.Run{{Text: "う\n\n\nえ"}}}}
+	var sb strings.Builder
+	if err := WriteSRT(&sb, cues); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "1\n00:00:01,000 --> 00:00:02,000\nう\nえ\n\n" {
+		t.Fatalf("unexpected SRT: %q", sb.String())
+	}
+	sb.Reset()
+	if err := WriteWebVTT(&sb, cues); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nう\nえ\n\n" {
+		t.Fatalf("unexpected WebVTT: %q", sb.String())
+	}
+}
+
+func TestCaptionExtractor(t *testing.T) {
+	e := NewCaptionExtractor(bytes.NewReader(nil))
+	e.handleFrame(&PMTFrame{ServiceID: 1, StreamList: []ESInfo{
+		{StreamId: StreamTypeH264, PID: 0x111, HasComponentTag: true, ComponentTag: 0x00},
+		{StreamId: StreamTypePESPrivate, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+	}})
+	base := uint64(1<<33 - 90000) // wraps around
+	// CS, SWF 7, SDF 620;480, SDP 170;30, SSM 36;36, SHS 4, SVS 24, APS row 7 column 4, RDF, あい
+	header := []byte("\x0c\x9b7 S\x9b620;480 V\x9b170;30 _\x9b36;36 W\x9b4 X\x9b24 Y")
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: base + 90000, Payload: captionStatementPES(0, append(header, 0x1c, 0x47, 0x44, 0x81, 0xa2, 0xa4))})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: base})
+	// CS only
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: base + 3*90000, Payload: captionStatementPES(0, []byte{0x0c})})
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: base + 4*90000, Payload: captionStatementPES(0, []byte{0x0c, 0xa6, 0x0d, 0xa8})})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: base + 10*90000})
+	e.closeAll()
+	cues := e.selectedCues()
+	if len(cues) != 2 {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+	if cues[0].Start != time.Second || cues[0].End != 3*time.Second || cues[0].Text() != "あい" {
+		t.Fatalf("unexpected cue: %+v", cues[0])
+	}
+	if cues[1].Start != 4*time.Second || cues[1].End != 10*time.Second || cues[1].Text() != "う\nえ" {
+		t.Fatalf("unexpected cue: %+v", cues[1])
+	}
+
+	var sb strings.Builder
+	if err := WriteSRT(&sb, cues); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "1\n00:00:01,000 --> 00:00:03,000\nあい\n\n2\n00:00:04,000 --> 00:00:10,000\nう\nえ\n\n" {
+		t.Fatalf("unexpected SRT: %q", sb.String())
+	}
+	sb.Reset()
+	if err := WriteWebVTT(&sb, cues); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(sb.String(), "WEBVTT\n\n00:00:01.000 --> 00:00:03.000\nあい\n\n") {
+		t.Fatalf("unexpected WebVTT: %q", sb.String())
+	}
+	sb.Reset()
+	if err := WriteASS(&sb, cues); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(sb.String(), "Dialogue: 0,0:00:01.00,0:00:03.00,Default,,0,0,0,,{\\an1\\pos(332,498)}{\\c&H0000FF&}あい\n") ||
+		!strings.Contains(sb.String(), "Dialogue: 0,0:00:04.00,0:00:10.00,Default,,0,0,0,,う\\Nえ\n") {
+		t.Fatalf("unexpected ASS: %s", sb.String())
+	}
+}
+
+func TestPESReassembly(t *testing.T) {
+	d := NewDecoder(bytes.NewReader(nil))
+	d.WatchPES(0x130, false)
+	pes := []byte{0, 0, 1, PrivateStream1ID, 0, 0, 0x80, 0x80, 5, 0x21, 0x00, 0x05, 0xbf, 0x21}
+	payload := bytes.Repeat([]byte{0x55}, 200)
+	pes = append(pes, payload...)
+	pes[4], pes[5] = byte((len(pes)-6)>>8), byte(len(pes)-6)
+	first := append([]byte{TsSyncCode, 0x41, 0x30, 0x10}, pes[:184]...)
+	second := append([]byte{TsSyncCode, 0x01, 0x30, 0x11}, pes[184:]...)
+	for len(second) < int(PacketLength) {
+		second = append(second, 0xff)
+	}
+	frame, err := d.pushPES(0x130, d.pesBuffer[0x130], first, true)
+	if err != nil || frame != nil {
+		t.Fatalf("PES should not be completed yet: %v %v", frame, err)
+	}
+	frame, err = d.pushPES(0x130, d.pesBuffer[0x130], second, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if frame == nil || !frame.HasPTS || frame.PTS != 90000 || !bytes.Equal(frame.Payload, payload) {
+		t.Fatalf("unexpected PES: %+v", frame)
+	}
+}
+
+func TestCaptionLanguages(t *testing.T) {
+	e := NewCaptionExtractor(bytes.NewReader(nil))
+	e.handleFrame(&PMTFrame{ServiceID: 1, StreamList: []ESInfo{
+		{StreamId: StreamTypePESPrivate, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+	}})
+	// jpn displayed automatically and eng selectable
+	management := append([]byte{0x00, 0x02, 0x00, 'j', 'p', 'n', 0x80, 0x28, 'e', 'n', 'g', 0x80}, captionDataUnits()...)
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 0, Payload: captionPES(0, management)})
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 90000, Payload: captionStatementPES(0, []byte{0xa2})})
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 90000, Payload: captionStatementPES(1, []byte{0x1b, 0x7e, 0xc1})})
+	e.closeAll()
+
+	languages := e.CaptionLanguages()
+	if len(languages) != 2 || languages[1].LangCode != "eng" || languages[0].ReceptionDisplayMode() != DisplayAuto || languages[1].ReceptionDisplayMode() != DisplaySelectable {
+		t.Fatalf("unexpected languages: %+v", languages)
+	}
+	e.Languages = []string{"eng"}
+	cues := e.selectedCues()
+	if len(cues) != 1 || cues[0].Text() != "A" {
+		t.Fatalf("unexpected cues: %+v", cues)
+	}
+
+	e.Languages = nil
+	base := t.TempDir() + "/programme"
+	paths, err := e.WriteSubtitleFiles(e.selectedCues(), base, SubtitleWebVTT)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) != 2 || paths[0] != base+".jpn.vtt" || paths[1] != base+".eng.vtt" {
+		t.Fatalf("unexpected paths: %v", paths)
+	}
+}
+
+func TestSuperimposeExtractor(t *testing.T) {
+	e := NewSuperimposeExtractor(bytes.NewReader(nil))
+	var notified []string
+	e.OnCue = func(cue CaptionCue) {
+		notified = append(notified, cue.Text())
+	}
+	e.handleFrame(&PMTFrame{ServiceID: 1, StreamList: []ESInfo{
+		{StreamId: StreamTypeH264, PID: 0x111, HasComponentTag: true, ComponentTag: 0x00},
+		{StreamId: StreamTypePESPrivate, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+		{StreamId: StreamTypePESPrivate, PID: 0x138, HasComponentTag: true, ComponentTag: 0x38},
+	}})
+	superimpose := captionStatementPES(0, []byte{0xa2})
+	superimpose[0] = SuperimposeDataIdentifier
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: 0})
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 90000, Payload: captionStatementPES(0, []byte{0xa4})})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: 2 * 90000})
+	// asynchronous PES without PTS, timed by the video
+	e.handleFrame(&PESFrame{PID: 0x138, Payload: superimpose})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: 20 * 90000})
+	e.closeAll()
+	if len(notified) != 1 || notified[0] != "あ" {
+		t.Fatalf("unexpected notification: %v", notified)
+	}
+	if len(e.cues) != 1 || e.cues[0].Start != 2*time.Second || e.cues[0].End != 20*time.Second {
+		t.Fatalf("unexpected cues: %+v", e.cues)
+	}
+}
+
+func TestCaptionStreamEvents(t *testing.T) {
+	e := NewCaptionExtractor(bytes.NewReader(nil))
+	e.live = true
+	var events []string
+	e.OnCue = func(cue CaptionCue) {
+		wallClock, _ := e.WallClockAt(cue.Start)
+		events = append(events, "cue "+cue.Text()+" "+wallClock.Format("15:04:05"))
+	}
+	e.OnErase = func(language uint8, at time.Duration) {
+		events = append(events, "erase "+at.String())
+	}
+	e.handleFrame(&PMTFrame{ServiceID: 1, StreamList: []ESInfo{
+		{StreamId: StreamTypeH264, PID: 0x111, HasComponentTag: true, ComponentTag: 0x00},
+		{StreamId: StreamTypePESPrivate, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+	}})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: 0})
+	e.handleFrame(&PESFrame{PID: 0x111, HasPTS: true, PTS: 90000})
+	tot, err := parseTOT([]byte{byte(TDTTID), 0x70, 0x05, 0xe8, 0xf7, 0x12, 0x34, 0x56}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tot.(*TOTFrame).JSTTime.Format("2006-01-02 15:04:05") != "2022-03-01 12:34:56" {
+		t.Fatalf("unexpected TDT: %v", tot.(*TOTFrame).JSTTime)
+	}
+	e.handleFrame(tot)
+	if _, err := parseTOT(nil, nil); err == nil {
+		t.Fatal("empty TOT should fail")
+	}
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 2 * 90000, Payload: captionStatementPES(0, []byte{0xa2})})
+	e.handleFrame(&PESFrame{PID: 0x130, HasPTS: true, PTS: 3 * 90000, Payload: captionStatementPES(0, []byte{0x0c})})
+	if len(events) != 2 || events[0] != "cue あ 12:34:57" || events[1] != "erase 3s" {
+		t.Fatalf("unexpected events: %v", events)
+	}
+	if len(e.cues) != 0 || e.PTSAt(3*time.Second) != 3*90000 {
+		t.Fatal("live extractor should not keep cues")
+	}
+}
+
+// failingReader gives a TS packet and then fails like a reset connection
+type failingReader struct {
+	packet []byte
+}
+
+func (r *failingReader) Read(p []byte) (int, error) {
+	if len(r.packet) == 0 {
+		return 0, errors.New("connection reset by peer")
+	}
+	n := copy(p, r.packet)
+	r.packet = r.packet[n:]
+	return n, nil
+}
+
+func TestCaptionStreamReadError(t *testing.T) {
+	// a broken packet is skipped, and the read error ends the stream
+	packet := bytes.Repeat([]byte{0xff}, int(PacketLength))
+	e := NewCaptionExtractor(&failingReader{packet})
+	err := e.Stream()
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
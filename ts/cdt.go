@@ -0,0 +1,261 @@
+package ts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"github.com/zlm2012/wildwrap/b24"
+	"hash/crc32"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+)
+
+const (
+	LogoTypeHDLarge uint8 = iota
+	LogoTypeHDSmall
+	LogoTypeSDLarge43
+	LogoTypeSDSmall43
+	LogoTypeSDLarge169
+	LogoTypeSDSmall169
+)
+
+const CDTDataTypeLogo uint8 = 0x01
+
+type CDTFrame struct {
+	TableID           TableID `json:"table_id"`
+	DownloadDataID    uint16  `json:"download_data_id"`
+	Version           uint8   `json:"version"`
+	CurrentNext       bool    `json:"current_next"`
+	Section           uint8   `json:"section"`
+	LastSection       uint8   `json:"last_section"`
+	OriginalNetworkID uint16  `json:"original_network_id"`
+	DataType          uint8   `json:"data_type"`
+	// UnknownDescriptors are the descriptors of the data, none of which is parsed
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
+	DataModule         []byte       `json:"data_module"`
+}
+
+func (f *CDTFrame) IsParsed() bool {
+	return true
+}
+
+func (f *CDTFrame) GetType() string {
+	return "CDT"
+}
+
+type LogoData struct {
+	OriginalNetworkID uint16
+	DownloadDataID    uint16
+	LogoType          uint8
+	LogoID            uint16
+	LogoVersion       uint16
+	DataSize          uint16
+	Data              []byte
+	// sections are the parts of Data by section_number, so that retransmitted sections are ignored
+	sections map[uint8][]byte
+}
+
+func (l *LogoData) IsComplete() bool {
+	return len(l.Data) >= int(l.DataSize)
+}
+
+// FileName gives the name in the same layout as EDCB LogoData: ONID_LogoID_LogoVersion_LogoType.png
+func (l *LogoData) FileName() string {
+	return fmt.Sprintf("%04X_%03X_%03X_%02X.png", l.OriginalNetworkID, l.LogoID, l.LogoVersion, l.LogoType)
+}
+
+// addSection puts the part of data in the order of section_number, ignoring the section already received
+func (l *LogoData) addSection(section uint8, data []byte) {
+	if _, ok := l.sections[section]; ok {
+		return
+	}
+	l.sections[section] = append([]byte{}, data...)
+	l.Data = make([]byte, 0, l.DataSize)
+	for i := 0; i < 256; i++ {
+		l.Data = append(l.Data, l.sections[uint8(i)]...)
+	}
+}
+
+// PNG returns the logo as a valid PNG, with PLTE and tRNS chunks of the default CLUT inserted after IHDR
+func (l *LogoData) PNG() ([]byte, error) {
+	return insertCLUTToPNG(l.Data[:l.DataSize])
+}
+
+func parseCDT(payload []byte, _ *Decoder) (Frame, error) {
+	if len(payload) < 17 || TableID(payload[0]) != CDTTID || payload[1]&0xf0 != 0xf0 {
+		return nil, errors.New("illegal CDT frame")
+	}
+	frame := CDTFrame{}
+	frame.TableID = TableID(payload[0])
+	frame.DownloadDataID = binary.BigEndian.Uint16(payload[3:5])
+	frame.Version = payload[5] & 0b111110 >> 1
+	frame.CurrentNext = payload[5]&1 == 1
+	frame.Section = payload[6]
+	frame.LastSection = payload[7]
+	frame.OriginalNetworkID = binary.BigEndian.Uint16(payload[8:10])
+	frame.DataType = payload[10]
+	descLen := binary.BigEndian.Uint16(payload[11:13]) & 0xfff
+	if 13+int(descLen) > len(payload)-4 {
+		return nil, errors.New("illegal CDT descriptors length")
+	}
+	descReader := bytes.NewReader(payload[13 : 13+descLen])
+	for {
+		tagID, tagContent, err := extractDescriptor(descReader)
+		if err != nil {
+			if err == io.EOF {
+				break
+			} else {
+				return nil, err
+			}
+		}
+		frame.UnknownDescriptors = append(frame.UnknownDescriptors, Descriptor{tagID, tagContent})
+	}
+	frame.DataModule = payload[13+descLen : len(payload)-4]
+	return &frame, nil
+}
+
+// LogoCollector reassembles logo data from CDT frames, keyed by ONID, download_data_id and logo type
+type LogoCollector struct {
+	logos map[logoKey]*LogoData
+}
+
+type logoKey struct {
+	onid           uint16
+	downloadDataID uint16
+	logoType       uint8
+}
+
+func NewLogoCollector() *LogoCollector {
+	return &LogoCollector{make(map[logoKey]*LogoData)}
+}
+
+func (c *LogoCollector) AddCDT(frame *CDTFrame) error {
+	if frame.DataType != CDTDataTypeLogo || !frame.CurrentNext {
+		return nil
+	}
+	module := frame.DataModule
+	if len(module) < 7 {
+		return errors.New("logo data module too short")
+	}
+	logoType := module[0]
+	key := logoKey{frame.OriginalNetworkID, frame.DownloadDataID, logoType}
+	logoID := binary.BigEndian.Uint16(module[1:3]) & 0x1ff
+	logoVersion := binary.BigEndian.Uint16(module[3:5]) & 0xfff
+	dataSize := binary.BigEndian.Uint16(module[5:7])
+	data := module[7:]
+
+	logo, ok := c.logos[key]
+	if ok && logo.LogoID == logoID && logo.LogoVersion == logoVersion && logo.DataSize == dataSize {
+		if logo.IsComplete() {
+			return nil
+		}
+		// continued from another section
+		logo.addSection(frame.Section, data)
+		return nil
+	}
+	logo = &LogoData{frame.OriginalNetworkID, frame.DownloadDataID, logoType, logoID, logoVersion, dataSize, nil, make(map[uint8][]byte)}
+	logo.addSection(frame.Section, data)
+	c.logos[key] = logo
+	return nil
+}
+
+// Logos returns all completely received logos
+func (c *LogoCollector) Logos() []*LogoData {
+	result := make([]*LogoData, 0, len(c.logos))
+	for _, logo := range c.logos {
+		if logo.IsComplete() {
+			result = append(result, logo)
+		}
+	}
+	return result
+}
+
+// FindLogo looks up the logo referred by the logo transmission descriptor in SDT
+func (c *LogoCollector) FindLogo(onid uint16, desc LogoTransmissionDescriptor, logoType uint8) *LogoData {
+	for _, logo := range c.logos {
+		if logo.OriginalNetworkID == onid && logo.LogoID == desc.LogoId && logo.LogoType == logoType && logo.IsComplete() {
+			return logo
+		}
+	}
+	return nil
+}
+
+// WritePNGs writes all completely received logos to dir
+func (c *LogoCollector) WritePNGs(dir string) error {
+	for _, logo := range c.Logos() {
+		png, err := logo.PNG()
+		if err != nil {
+			log.Printf("failed on fixing logo %s: %v", logo.FileName(), err)
+			continue
+		}
+		err = os.WriteFile(filepath.Join(dir, logo.FileName()), png, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var pngSignature = []byte{0x89, 'P', 'N', 'G', '\r', '\n', 0x1a, '\n'}
+
+func insertCLUTToPNG(raw []byte) ([]byte, error) {
+	if len(raw) < len(pngSignature)+25 || !bytes.Equal(raw[:len(pngSignature)], pngSignature) {
+		return nil, errors.New("not a png")
+	}
+	// IHDR is always the first chunk, 4 bytes length + 4 bytes type + 13 bytes data + 4 bytes crc
+	ihdrEnd := len(pngSignature) + 25
+	if string(raw[12:16]) != "IHDR" {
+		return nil, errors.New("IHDR not found")
+	}
+	hasPLTE, err := hasPNGChunk(raw[len(pngSignature):], "PLTE")
+	if err != nil {
+		return nil, err
+	}
+	if hasPLTE {
+		return raw, nil
+	}
+	plte := make([]byte, 0, len(b24.DefaultCLUT)*3)
+	trns := make([]byte, 0, len(b24.DefaultCLUT))
+	for _, c := range b24.DefaultCLUT {
+		r, g, b, a := c.RGBA()
+		plte = append(plte, uint8(r>>8), uint8(g>>8), uint8(b>>8))
+		trns = append(trns, uint8(a>>8))
+	}
+	result := make([]byte, 0, len(raw)+len(plte)+len(trns)+24)
+	result = append(result, raw[:ihdrEnd]...)
+	result = appendPNGChunk(result, "PLTE", plte)
+	result = appendPNGChunk(result, "tRNS", trns)
+	result = append(result, raw[ihdrEnd:]...)
+	return result, nil
+}
+
+// hasPNGChunk walks the chunks after the signature, and reports whether there is a chunk of the type
+func hasPNGChunk(chunks []byte, chunkType string) (bool, error) {
+	for len(chunks) > 0 {
+		if len(chunks) < 12 {
+			return false, errors.New("png chunk truncated")
+		}
+		chunkLen := int(binary.BigEndian.Uint32(chunks[0:4]))
+		if chunkLen > len(chunks)-12 {
+			return false, errors.New("png chunk truncated")
+		}
+		if string(chunks[4:8]) == chunkType {
+			return true, nil
+		}
+		chunks = chunks[12+chunkLen:]
+	}
+	return false, nil
+}
+
+func appendPNGChunk(buf []byte, chunkType string, data []byte) []byte {
+	lenBuf := make([]byte, 4)
+	binary.BigEndian.PutUint32(lenBuf, uint32(len(data)))
+	buf = append(buf, lenBuf...)
+	chunk := append([]byte(chunkType), data...)
+	buf = append(buf, chunk...)
+	binary.BigEndian.PutUint32(lenBuf, crc32.ChecksumIEEE(chunk))
+	return append(buf, lenBuf...)
+}
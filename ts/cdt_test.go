@@ -0,0 +1,109 @@
+package ts
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+func stripPNGChunks(raw []byte, chunkTypes ...string) []byte {
+	result := append([]byte{}, raw[:8]...)
+	raw = raw[8:]
+	for len(raw) > 0 {
+		chunkLen := int(binary.BigEndian.Uint32(raw[0:4])) + 12
+		chunkType := string(raw[4:8])
+		keep := true
+		for _, t := range chunkTypes {
+			if t == chunkType {
+				keep = false
+			}
+		}
+		if keep {
+			result = append(result, raw[:chunkLen]...)
+		}
+		raw = raw[chunkLen:]
+	}
+	return result
+}
+
+func TestLogoCollectorPNG(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 4, 2), b24.DefaultCLUT)
+	img.SetColorIndex(1, 1, 7)
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	broadcastPNG := stripPNGChunks(buf.Bytes(), "PLTE", "tRNS")
+	if _, err := png.Decode(bytes.NewReader(broadcastPNG)); err == nil {
+		t.Fatal("png without PLTE is expected to be invalid")
+	}
+
+	header := []byte{LogoTypeHDLarge, 0xFE, 0x01, 0xF0, 0x02, 0, 0}
+	binary.BigEndian.PutUint16(header[5:7], uint16(len(broadcastPNG)))
+	half := len(broadcastPNG) / 2
+	collector := NewLogoCollector()
+	first := &CDTFrame{CurrentNext: true, LastSection: 1, OriginalNetworkID: 4, DownloadDataID: 1, DataType: CDTDataTypeLogo, DataModule: append(header, broadcastPNG[:half]...)}
+	_ = collector.AddCDT(first)
+	// retransmitted
+	_ = collector.AddCDT(first)
+	if len(collector.Logos()) != 0 {
+		t.Fatal("logo should not be complete yet")
+	}
+	_ = collector.AddCDT(&CDTFrame{CurrentNext: true, Section: 1, LastSection: 1, OriginalNetworkID: 4, DownloadDataID: 1, DataType: CDTDataTypeLogo, DataModule: append(header, broadcastPNG[half:]...)})
+	logo := collector.FindLogo(4, LogoTransmissionDescriptor{LogoId: 0x001}, LogoTypeHDLarge)
+	if logo == nil {
+		t.Fatal("logo not found")
+	}
+	if logo.FileName() != "0004_001_002_00.png" {
+		t.Fatalf("unexpected file name: %s", logo.FileName())
+	}
+	fixed, err := logo.PNG()
+	if err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := png.Decode(bytes.NewReader(fixed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r, g, b, a := decoded.At(1, 1).RGBA()
+	if r != 0xffff || g != 0xffff || b != 0xffff || a != 0xffff {
+		t.Fatalf("unexpected color: %v", decoded.At(1, 1))
+	}
+}
+
+func TestInsertCLUTToPNGSkipsChunkData(t *testing.T) {
+	img := image.NewPaletted(image.Rect(0, 0, 4, 2), b24.DefaultCLUT)
+	buf := bytes.Buffer{}
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	stripped := stripPNGChunks(buf.Bytes(), "PLTE", "tRNS")
+	// a text chunk mentioning PLTE after IHDR
+	broadcastPNG := appendPNGChunk(append([]byte{}, stripped[:33]...), "tEXt", []byte("Comment\x00PLTE"))
+	broadcastPNG = append(broadcastPNG, stripped[33:]...)
+	fixed, err := insertCLUTToPNG(broadcastPNG)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := png.Decode(bytes.NewReader(fixed)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseCDTTruncated(t *testing.T) {
+	if _, err := parseCDT([]byte{byte(CDTTID), 0xf0, 0, 0, 1}, nil); err == nil {
+		t.Fatal("short CDT should fail")
+	}
+	cdt := []byte{byte(CDTTID), 0xf0, 14, 0, 1, 0xc1, 0, 0, 0, 4, CDTDataTypeLogo, 0xff, 0xff, 0, 0, 0, 0}
+	if _, err := parseCDT(cdt, nil); err == nil {
+		t.Fatal("CDT with oversized descriptors length should fail")
+	}
+	cdt[11], cdt[12] = 0xf0, 0
+	if _, err := parseCDT(cdt, nil); err != nil {
+		t.Fatal(err)
+	}
+}
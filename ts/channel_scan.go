@@ -0,0 +1,293 @@
+package ts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	ChannelTypeGR  = "GR"
+	ChannelTypeBS  = "BS"
+	ChannelTypeCS  = "CS"
+	ChannelTypeSKY = "SKY"
+)
+
+type ScannedService struct {
+	ServiceID        uint16      `json:"serviceId"`
+	Name             string      `json:"name,omitempty"`
+	ProviderName     string      `json:"providerName,omitempty"`
+	ServiceType      ServiceType `json:"serviceType"`
+	PartialReception bool        `json:"partialReception,omitempty"`
+}
+
+type ScannedChannel struct {
+	Name               string           `json:"name"`
+	Type               string           `json:"type"`
+	Channel            string           `json:"channel"`
+	NetworkID          uint16           `json:"networkId"`
+	TransportStreamID  uint16           `json:"transportStreamId"`
+	RemoteControlKeyID uint8            `json:"remoteControlKeyId,omitempty"`
+	FrequencyMHz       float64          `json:"frequencyMHz,omitempty"`
+	Polarization       string           `json:"polarization,omitempty"`
+	OrbitalPosition    float64          `json:"orbitalPosition,omitempty"`
+	AreaCode           uint16           `json:"areaCode,omitempty"`
+	Services           []ScannedService `json:"services"`
+}
+
+type ChannelScanReport struct {
+	NetworkName string           `json:"networkName"`
+	Channels    []ScannedChannel `json:"channels"`
+}
+
+type scanKey struct {
+	onid uint16
+	tsid uint16
+}
+
+type sectionKey struct {
+	tableID TableID
+	id      uint16
+}
+
+// ChannelScanner collects NIT and SDT frames and joins them into a ChannelScanReport
+type ChannelScanner struct {
+	networkName string
+	transports  map[scanKey]*NITTransportEntry
+	sdtEntries  map[scanKey]map[uint16]SDTFrameEntry
+	sections    map[sectionKey]map[uint8]bool
+	lastSection map[sectionKey]uint8
+}
+
+func NewChannelScanner() *ChannelScanner {
+	return &ChannelScanner{
+		transports:  make(map[scanKey]*NITTransportEntry),
+		sdtEntries:  make(map[scanKey]map[uint16]SDTFrameEntry),
+		sections:    make(map[sectionKey]map[uint8]bool),
+		lastSection: make(map[sectionKey]uint8),
+	}
+}
+
+func (s *ChannelScanner) markSection(key sectionKey, section uint8, lastSection uint8) {
+	if _, ok := s.sections[key]; !ok {
+		s.sections[key] = make(map[uint8]bool)
+	}
+	s.sections[key][section] = true
+	s.lastSection[key] = lastSection
+}
+
+func (s *ChannelScanner) isComplete(tableID TableID) bool {
+	found := false
+	for key, sections := range s.sections {
+		if key.tableID != tableID {
+			continue
+		}
+		found = true
+		for i := 0; i <= int(s.lastSection[key]); i++ {
+			if !sections[uint8(i)] {
+				return false
+			}
+		}
+	}
+	return found
+}
+
+// IsComplete reports whether every section of actual NIT and actual SDT has been collected
+func (s *ChannelScanner) IsComplete() bool {
+	return s.isComplete(NITActualTID) && s.isComplete(SDTActualTID)
+}
+
+func (s *ChannelScanner) AddNIT(frame *NITFrame) {
+	if !frame.CurrentNext {
+		return
+	}
+	if s.networkName == "" {
+		s.networkName = frame.NetworkName
+	}
+	s.markSection(sectionKey{frame.TableID, frame.NetworkID}, frame.Section, frame.LastSection)
+	for i := range frame.TransportStreams {
+		entry := frame.TransportStreams[i]
+		if entry.NetworkName == "" {
+			entry.NetworkName = frame.NetworkName
+		}
+		s.transports[scanKey{entry.OriginalNetworkId, entry.TransportStreamId}] = &entry
+	}
+}
+
+func (s *ChannelScanner) AddSDT(frame *SDTFrame) {
+	if !frame.CurrentNext {
+		return
+	}
+	s.markSection(sectionKey{frame.TableID, frame.TransportStreamID}, frame.Section, frame.LastSection)
+	key := scanKey{frame.OriginalNetworkID, frame.TransportStreamID}
+	if _, ok := s.sdtEntries[key]; !ok {
+		s.sdtEntries[key] = make(map[uint16]SDTFrameEntry)
+	}
+	for _, entry := range frame.Entries {
+		s.sdtEntries[key][entry.ServiceID] = entry
+	}
+}
+
+// ChannelTypeOf guesses the Mirakurun channel type from the original network id
+func ChannelTypeOf(onid uint16) string {
+	switch {
+	case onid == 0x0004:
+		return ChannelTypeBS
+	case onid == 0x0006 || onid == 0x0007:
+		return ChannelTypeCS
+	case 0x7880 <= onid && onid <= 0x7FE8:
+		return ChannelTypeGR
+	default:
+		return ChannelTypeSKY
+	}
+}
+
+func channelOf(channelType string, entry *NITTransportEntry) string {
+	switch channelType {
+	case ChannelTypeGR:
+		if entry.Terrestrial != nil && len(entry.Terrestrial.Frequencies) > 0 {
+			return strconv.Itoa(entry.Terrestrial.UHFChannel())
+		}
+	case ChannelTypeBS:
+		// TSID is laid out as 0x4000 | transponder << 4 | slot
+		return fmt.Sprintf("BS%02d_%d", entry.TransportStreamId>>4&0x1f, entry.TransportStreamId&0x7)
+	case ChannelTypeCS:
+		return fmt.Sprintf("CS%d", entry.TransportStreamId>>4&0x1f)
+	}
+	return fmt.Sprintf("%04X", entry.TransportStreamId)
+}
+
+func (s *ChannelScanner) Report() *ChannelScanReport {
+	report := ChannelScanReport{NetworkName: s.networkName, Channels: make([]ScannedChannel, 0)}
+	keys := make(map[scanKey]bool)
+	for key := range s.transports {
+		keys[key] = true
+	}
+	for key := range s.sdtEntries {
+		keys[key] = true
+	}
+	for key := range keys {
+		entry, ok := s.transports[key]
+		if !ok {
+			entry = &NITTransportEntry{TransportStreamId: key.tsid, OriginalNetworkId: key.onid}
+		}
+		channel := ScannedChannel{}
+		channel.Type = ChannelTypeOf(key.onid)
+		if entry.Terrestrial != nil {
+			channel.Type = ChannelTypeGR
+			channel.AreaCode = entry.Terrestrial.AreaCode
+			if len(entry.Terrestrial.Frequencies) > 0 {
+				channel.FrequencyMHz = entry.Terrestrial.FrequenciesMHz()[0]
+			}
+		}
+		if entry.Satellite != nil {
+			channel.FrequencyMHz = entry.Satellite.FrequencyMHz()
+			channel.Polarization = entry.Satellite.Polarization.String()
+			channel.OrbitalPosition = entry.Satellite.OrbitalPositionDegree()
+		}
+		channel.Channel = channelOf(channel.Type, entry)
+		channel.NetworkID = key.onid
+		channel.TransportStreamID = key.tsid
+		channel.RemoteControlKeyID = entry.TSInfo.RemoteControlKeyId
+		channel.Services = make([]ScannedService, 0)
+
+		partial := make(map[uint16]bool)
+		for _, sid := range entry.PartialReceptionServices {
+			partial[sid] = true
+		}
+		sids := make(map[uint16]bool)
+		for sid := range entry.ServiceList {
+			sids[sid] = true
+		}
+		for sid := range s.sdtEntries[key] {
+			sids[sid] = true
+		}
+		for sid := range sids {
+			service := ScannedService{ServiceID: sid, ServiceType: entry.ServiceList[sid], PartialReception: partial[sid]}
+			if sdtEntry, ok := s.sdtEntries[key][sid]; ok {
+				service.Name = sdtEntry.Service.ServiceName
+				service.ProviderName = sdtEntry.Service.ServiceProviderName
+				service.ServiceType = sdtEntry.Service.ServiceType
+			}
+			channel.Services = append(channel.Services, service)
+		}
+		sort.Slice(channel.Services, func(i, j int) bool {
+			return channel.Services[i].ServiceID < channel.Services[j].ServiceID
+		})
+
+		channel.Name = entry.TSInfo.TSName
+		if channel.Name == "" {
+			for _, service := range channel.Services {
+				if service.Name != "" {
+					channel.Name = service.Name
+					break
+				}
+			}
+		}
+		if channel.Name == "" {
+			channel.Name = entry.NetworkName
+		}
+		report.Channels = append(report.Channels, channel)
+	}
+	sort.Slice(report.Channels, func(i, j int) bool {
+		if report.Channels[i].NetworkID != report.Channels[j].NetworkID {
+			return report.Channels[i].NetworkID < report.Channels[j].NetworkID
+		}
+		return report.Channels[i].TransportStreamID < report.Channels[j].TransportStreamID
+	})
+	return &report
+}
+
+func (r *ChannelScanReport) WriteJSON(w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(r)
+}
+
+// WriteYAML writes the channels in the format of Mirakurun channels.yml
+func (r *ChannelScanReport) WriteYAML(w io.Writer) error {
+	for _, channel := range r.Channels {
+		_, err := fmt.Fprintf(w, "- name: %s\n  type: %s\n  channel: %s\n  isDisabled: false\n",
+			yamlQuote(channel.Name), channel.Type, yamlQuote(channel.Channel))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func yamlQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// ScanChannels reads frames until every section of actual NIT and actual SDT is collected or the reader is drained.
+// Broken sections are skipped, and the ReadError is returned when the input fails.
+func (d *Decoder) ScanChannels() (*ChannelScanReport, error) {
+	scanner := NewChannelScanner()
+	for !scanner.IsComplete() {
+		frame, err := d.ParseNext()
+		if err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+			var readErr *ReadError
+			if errors.As(err, &readErr) {
+				return nil, err
+			}
+			log.Printf("failed on parsing frame while scanning channels: %v", err)
+			continue
+		}
+		switch f := frame.(type) {
+		case *NITFrame:
+			scanner.AddNIT(f)
+		case *SDTFrame:
+			scanner.AddSDT(f)
+		}
+	}
+	return scanner.Report(), nil
+}
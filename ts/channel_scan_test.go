@@ -0,0 +1,128 @@
+package ts
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+func TestParseSatelliteDeliverySystemDescriptor(t *testing.T) {
+	desc := parseSatelliteDeliverySystemDescriptor([]byte{0x01, 0x17, 0x27, 0x48, 0x11, 0x00, 0xE8, 0x02, 0x88, 0x60, 0x0F})
+	if desc.Frequency != 1172748 || desc.OrbitalPosition != 1100 || !desc.East {
+		t.Fatalf("unexpected descriptor: %+v", *desc)
+	}
+	if desc.Polarization != PolarizationCircularRight || desc.Modulation != 0x08 || desc.SymbolRate != 288600 || desc.FECInner != 0xF {
+		t.Fatalf("unexpected descriptor: %+v", *desc)
+	}
+}
+
+func TestChannelScanReport(t *testing.T) {
+	scanner := NewChannelScanner()
+	scanner.AddNIT(&NITFrame{TableID: NITActualTID, NetworkID: 0x7FE0, CurrentNext: true, NetworkName: "TEST",
+		TransportStreams: []NITTransportEntry{{
+			TransportStreamId:        0x7FE0,
+			OriginalNetworkId:        0x7FE0,
+			ServiceList:              map[uint16]ServiceType{0x400: 0x01, 0x5C8: 0xC0},
+			TSInfo:                   TSInfo{1, "テスト"},
+			Terrestrial:              &TerrestrialDeliverySystemDescriptor{Frequencies: []uint16{(473+6*14)*7 + 1}},
+			PartialReceptionServices: []uint16{0x5C8},
+		}}})
+	if scanner.IsComplete() {
+		t.Fatal("scan should not be complete without SDT")
+	}
+	scanner.AddSDT(&SDTFrame{TableID: SDTActualTID, TransportStreamID: 0x7FE0, OriginalNetworkID: 0x7FE0, CurrentNext: true,
+		Entries: []SDTFrameEntry{{ServiceID: 0x400, Service: ServiceDescriptor{0x01, "", "テスト1"}}}})
+	if !scanner.IsComplete() {
+		t.Fatal("scan should be complete")
+	}
+	report := scanner.Report()
+	if len(report.Channels) != 1 {
+		t.Fatalf("unexpected channels: %v", report.Channels)
+	}
+	channel := report.Channels[0]
+	if channel.Type != ChannelTypeGR || channel.Channel != "27" || len(channel.Services) != 2 || !channel.Services[1].PartialReception {
+		t.Fatalf("unexpected channel: %+v", channel)
+	}
+	buf := bytes.Buffer{}
+	if err := report.WriteYAML(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != "- name: 'テスト'\n  type: GR\n  channel: '27'\n  isDisabled: false\n" {
+		t.Fatalf("unexpected yaml: %s", buf.String())
+	}
+}
+
+func TestParseNIT(t *testing.T) {
+	name, err := b24.EncodeString("テスト")
+	if err != nil {
+		t.Fatal(err)
+	}
+	networkDesc := append([]byte{NetworkNameDescTagID, byte(len(name))}, name...)
+	networkDesc = append(networkDesc,
+		SystemManagementDescTagID, 2, 0x03, 0x01,
+		SystemManagementDescTagID, 1, 0x03)
+	tsDesc := append([]byte{TSInfoDescTagID, byte(2 + len(name)), 0x01, byte(len(name) << 2)}, name...)
+	tsDesc = append(tsDesc,
+		ServiceListDescTagID, 6, 0x04, 0x00, 0x01, 0x05, 0xc8, 0xc0,
+		// area code 0x123, guard interval 1/8, mode 3
+		TerrestrialDescTagID, 4, 0x12, 0x3b, 0x0f, 0x3c,
+		PartialReceptionDescTagID, 2, 0x05, 0xc8,
+		SystemManagementDescTagID, 2, 0x03, 0x01)
+	truncatedDesc := []byte{
+		TerrestrialDescTagID, 1, 0x12,
+		TSInfoDescTagID, 2, 0x01, 0xfc,
+		SatelliteDescTagID, 3, 0x01, 0x17, 0x27,
+		DataComponentDescTagID, 1, 0x00,
+		SystemManagementDescTagID, 1, 0x03}
+
+	nit := []byte{byte(NITActualTID), 0xf0, 0, 0x7f, 0xe0, 0xc1, 0, 0, 0xf0, byte(len(networkDesc))}
+	nit = append(nit, networkDesc...)
+	loopLen := 12 + len(tsDesc) + len(truncatedDesc)
+	nit = append(nit, 0xf0, byte(loopLen), 0x7f, 0xe0, 0x7f, 0xe0, 0xf0, byte(len(tsDesc)))
+	nit = append(nit, tsDesc...)
+	nit = append(nit, 0x7f, 0xe1, 0x7f, 0xe0, 0xf0, byte(len(truncatedDesc)))
+	nit = append(nit, truncatedDesc...)
+	nit = append(nit, 0, 0, 0, 0)
+
+	frame, err := parseNIT(nit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	nitFrame := frame.(*NITFrame)
+	if nitFrame.NetworkID != 0x7fe0 || nitFrame.NetworkName != "テスト" || len(nitFrame.SystemManagement) != 1 ||
+		nitFrame.SystemManagement[0].BroadcastingIdentifier != 0x03 || len(nitFrame.UnknownDescriptors) != 1 || len(nitFrame.TransportStreams) != 2 {
+		t.Fatalf("unexpected NIT: %+v", nitFrame)
+	}
+	entry := nitFrame.TransportStreams[0]
+	if entry.TSInfo.RemoteControlKeyId != 1 || entry.TSInfo.TSName != "テスト" || len(entry.ServiceList) != 2 || entry.ServiceList[0x5c8] != 0xc0 ||
+		len(entry.PartialReceptionServices) != 1 || entry.PartialReceptionServices[0] != 0x5c8 || len(entry.SystemManagement) != 1 {
+		t.Fatalf("unexpected transport stream: %+v", entry)
+	}
+	terrestrial := entry.Terrestrial
+	if terrestrial == nil || terrestrial.AreaCode != 0x123 || terrestrial.GuardInterval != 0b10 || terrestrial.TransmissionMode != 0b11 ||
+		len(terrestrial.Frequencies) != 1 || terrestrial.Frequencies[0] != (473+6*14)*7+1 {
+		t.Fatalf("unexpected terrestrial delivery system descriptor: %+v", terrestrial)
+	}
+	truncated := nitFrame.TransportStreams[1]
+	if truncated.Terrestrial != nil || truncated.Satellite != nil || truncated.TSInfo.RemoteControlKeyId != 0 ||
+		len(truncated.DataComponents) != 0 || len(truncated.SystemManagement) != 0 || len(truncated.UnknownDescriptors) != 5 {
+		t.Fatalf("unexpected transport stream of truncated descriptors: %+v", truncated)
+	}
+
+	nit[len(nit)-4-len(truncatedDesc)-1] = byte(len(truncatedDesc) + 1)
+	if _, err := parseNIT(nit, nil); err == nil {
+		t.Fatal("descriptors length over the loop should be an error")
+	}
+}
+
+func TestScanChannelsReadError(t *testing.T) {
+	// a broken packet is skipped, and the read error ends the scan
+	packet := bytes.Repeat([]byte{0xff}, int(PacketLength))
+	_, err := NewDecoder(&failingReader{packet}).ScanChannels()
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
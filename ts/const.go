@@ -11,15 +11,29 @@ const (
 	CounterMask         uint8  = 0xf
 
 	EITPID uint16 = 0x12
+	TOTPID uint16 = 0x14
+	BITPID uint16 = 0x24
+	CDTPID uint16 = 0x29
 
-	PATTID                 uint8 = 0x00
-	PMTTID                 uint8 = 0x02
-	EITCurrentStreamTID    uint8 = 0x4E
-	EITOtherStreamTID      uint8 = 0x4F
-	EITCurrentSchedTIDMask uint8 = 0x50
-	EITOtherSchedTIDMask   uint8 = 0x60
+	PATTID                 TableID = 0x00
+	PMTTID                 TableID = 0x02
+	NITActualTID           TableID = 0x40
+	NITOtherTID            TableID = 0x41
+	SDTActualTID           TableID = 0x42
+	SDTOtherTID            TableID = 0x46
+	TDTTID                 TableID = 0x70
+	TOTTID                 TableID = 0x73
+	BITTID                 TableID = 0xC4
+	CDTTID                 TableID = 0xC8
+	EITCurrentStreamTID    TableID = 0x4E
+	EITOtherStreamTID      TableID = 0x4F
+	EITCurrentSchedTIDMask TableID = 0x50
+	EITOtherSchedTIDMask   TableID = 0x60
 
-	RestrictDescTagID uint8 = 0x09
+	RestrictDescTagID         uint8 = 0x09
+	ISO639LangDescTagID       uint8 = 0x0A
+	StreamIdentifierDescTagID uint8 = 0x52
+	DataComponentDescTagID    uint8 = 0xFD
 
 	NetworkNameDescTagID   uint8 = 0x40
 	ServiceListDescTagID   uint8 = 0x41
@@ -37,6 +51,13 @@ const (
 	ParentRateDescTagID
 	HyperlinkDescTagID
 
+	ExtendedBroadcasterDescTagID uint8 = 0xCE
+	SIParameterDescTagID         uint8 = 0xD7
+	BroadcasterNameDescTagID     uint8 = 0xD8
+	TerrestrialDescTagID         uint8 = 0xFA
+	PartialReceptionDescTagID    uint8 = 0xFB
+	SystemManagementDescTagID    uint8 = 0xFE
+
 	AnimeGenreIDMask uint8 = 0x70
 	TokusatuGenreID  uint8 = 0x72
 
@@ -44,6 +65,25 @@ const (
 	AudioCompDualMonoModeID uint8 = 0b00010
 )
 
+const (
+	StreamTypeMPEG1Video StreamType = 0x01
+	StreamTypeMPEG2Video StreamType = 0x02
+	StreamTypePESPrivate StreamType = 0x06
+	StreamTypeDSMCCTypeB StreamType = 0x0B
+	StreamTypeDSMCCTypeD StreamType = 0x0D
+	StreamTypeAACADTS    StreamType = 0x0F
+	StreamTypeAACLATM    StreamType = 0x11
+	StreamTypeH264       StreamType = 0x1B
+	StreamTypeH265       StreamType = 0x24
+)
+
+const (
+	PolarizationLinearHorizontal Polarization = iota
+	PolarizationLinearVertical
+	PolarizationCircularLeft
+	PolarizationCircularRight
+)
+
 const (
 	RSUndefined SDTRunningState = iota
 	RSNotRunning
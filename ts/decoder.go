@@ -15,6 +15,7 @@ type frameBuffer struct {
 	buf         []byte
 	lastCounter uint8
 	sectionLen  uint16
+	offset      int64
 }
 
 type Decoder struct {
@@ -24,6 +25,12 @@ type Decoder struct {
 	selectedSid uint16
 	pidBuffer   map[uint16]*frameBuffer
 	pidToParse  map[uint16]func([]byte, *Decoder) (Frame, error)
+	pesBuffer   map[uint16]*pesBuffer
+	stats       *PacketStats
+	// offset is of the next packet to read, and packetOffset is of the last packet read
+	offset       int64
+	packetOffset int64
+	lastSection  SectionInfo
 }
 
 type Frame interface {
@@ -31,8 +38,31 @@ type Frame interface {
 	GetType() string
 }
 
+// SectionInfo tells where the last frame given by ParseNext came from
+type SectionInfo struct {
+	PID uint16
+	// TableID is the table ID of PSI/SI, or the stream ID of PES
+	TableID uint8
+	// Offset is the byte offset in the input of the first TS packet carrying the section
+	Offset int64
+}
+
+// ReadError is given by ParseNext when the input fails, unlike the errors of broken packets and sections after which
+// parsing can go on. io.EOF and io.ErrUnexpectedEOF at the end of the input are given as is.
+type ReadError struct {
+	Err error
+}
+
+func (e *ReadError) Error() string {
+	return "failed on reading TS: " + e.Err.Error()
+}
+
+func (e *ReadError) Unwrap() error {
+	return e.Err
+}
+
 type GeneralFrame struct {
-	RawData []byte
+	RawData []byte `json:"raw_data"`
 }
 
 func (f *GeneralFrame) IsParsed() bool {
@@ -44,7 +74,12 @@ func (f *GeneralFrame) GetType() string {
 }
 
 func NewDecoder(reader io.Reader) *Decoder {
-	return &Decoder{reader, nil, make(map[uint16]*PMTFrame), 0, make(map[uint16]*frameBuffer), map[uint16]func([]byte, *Decoder) (Frame, error){0x0: parsePAT, 0x10: parseNIT, 0x11: parseSDT, 0x12: parseEIT}}
+	return &Decoder{reader, nil, make(map[uint16]*PMTFrame), 0, make(map[uint16]*frameBuffer), map[uint16]func([]byte, *Decoder) (Frame, error){0x0: parsePAT, 0x10: parseNIT, 0x11: parseSDT, 0x12: parseEIT, TOTPID: parseTOT, CDTPID: parseCDT, BITPID: parseBIT}, make(map[uint16]*pesBuffer), nil, 0, 0, SectionInfo{}}
+}
+
+// LastSection tells where the last frame given by ParseNext came from
+func (d *Decoder) LastSection() SectionInfo {
+	return d.lastSection
 }
 
 func (d *Decoder) ParseNext() (Frame, error) {
@@ -58,6 +93,16 @@ func (d *Decoder) ParseNext() (Frame, error) {
 		FlagPIDCombo := binary.BigEndian.Uint16(buf[1:3])
 		isPUSI = PUSI&FlagPIDCombo == PUSI
 		PID = FlagPIDCombo & PIDMask
+		if pesBuf, ok := d.pesBuffer[PID]; ok {
+			frame, err := d.pushPES(PID, pesBuf, buf, isPUSI)
+			if err != nil {
+				return nil, err
+			}
+			if frame != nil {
+				return frame, nil
+			}
+			continue
+		}
 		if _, ok := d.pidToParse[PID]; !ok {
 			continue
 		}
@@ -74,7 +119,7 @@ func (d *Decoder) ParseNext() (Frame, error) {
 				payloadOffset := payload[0]
 				newPayload := payload[1+payloadOffset:]
 				sectionLen := binary.BigEndian.Uint16(newPayload[1:3])&0xfff + 3
-				d.pidBuffer[PID] = &frameBuffer{newPayload, counter, sectionLen}
+				d.pidBuffer[PID] = &frameBuffer{newPayload, counter, sectionLen, d.packetOffset}
 			} else if pidBuf.lastCounter == counter {
 				continue
 			} else if (pidBuf.lastCounter == 0xf && counter == 0) || pidBuf.lastCounter+1 == counter {
@@ -83,13 +128,14 @@ func (d *Decoder) ParseNext() (Frame, error) {
 					payloadOffset := payload[0]
 					newPayload := payload[1+payloadOffset:]
 					sectionLen := binary.BigEndian.Uint16(newPayload[1:3])&0xfff + 3
-					d.pidBuffer[PID] = &frameBuffer{newPayload, counter, sectionLen}
+					d.pidBuffer[PID] = &frameBuffer{newPayload, counter, sectionLen, d.packetOffset}
 
 					// Parse
 					parseFunc, _ := d.pidToParse[PID]
 					if payloadOffset != 0 {
 						pidBuf.buf = append(pidBuf.buf, payload[1:1+payloadOffset]...)
 					}
+					d.lastSection = SectionInfo{PID, pidBuf.buf[0], pidBuf.offset}
 					return parseFunc(pidBuf.buf[0:pidBuf.sectionLen], d)
 				} else {
 					pidBuf.lastCounter = counter
@@ -105,11 +151,13 @@ func (d *Decoder) ParseNext() (Frame, error) {
 }
 
 func parseNIT(payload []byte, _ *Decoder) (Frame, error) {
-	if (payload[0] != 0x40 && payload[0] != 0x41) || payload[1]&0xf0 != 0xf0 {
+	if len(payload) < 16 || (TableID(payload[0]) != NITActualTID && TableID(payload[0]) != NITOtherTID) || payload[1]&0xf0 != 0xf0 {
 		return nil, errors.New("illegal NIT frame")
 	}
 
 	frame := NITFrame{}
+	text := textDecoder{}
+	frame.TableID = TableID(payload[0])
 	frame.TransportStreams = make([]NITTransportEntry, 0)
 	frame.NetworkID = binary.BigEndian.Uint16(payload[3:5])
 	frame.Version = payload[5] & 0b111110 >> 1
@@ -117,6 +165,9 @@ func parseNIT(payload []byte, _ *Decoder) (Frame, error) {
 	frame.Section = payload[6]
 	frame.LastSection = payload[7]
 	networkDescLen := binary.BigEndian.Uint16(payload[8:10]) & 0xfff
+	if int(networkDescLen)+16 > len(payload) {
+		return nil, errors.New("illegal NIT network descriptors length")
+	}
 
 	// network descriptor
 	networkDescSlice := payload[10 : 10+networkDescLen]
@@ -131,27 +182,34 @@ func parseNIT(payload []byte, _ *Decoder) (Frame, error) {
 				return nil, err
 			}
 		}
-		switch tagID {
-		case NetworkNameDescTagID:
-			name, err := b24.DecodeString(tagContent)
-			if err != nil {
-				return nil, err
-			}
+		// descriptors too short to be parsed are left as unknown ones
+		switch {
+		case tagID == NetworkNameDescTagID:
+			name := text.decode(tagContent)
 			frame.NetworkName = name
-		case 0xFE:
-			// ignore
+		case tagID == SystemManagementDescTagID && len(tagContent) >= 2:
+			frame.SystemManagement = append(frame.SystemManagement, parseSystemManagementDescriptor(tagContent))
 		default:
-			log.Printf("NIT Network tagID: %x, content: %v", tagID, tagContent)
+			frame.UnknownDescriptors = append(frame.UnknownDescriptors, Descriptor{tagID, tagContent})
 		}
 	}
 	tsLoopLen := binary.BigEndian.Uint16(payload[0:2]) & 0xfff
+	if int(tsLoopLen)+6 > len(payload) {
+		return nil, errors.New("illegal NIT transport stream loop length")
+	}
 	tsPayload := payload[2 : 2+tsLoopLen]
 	for len(tsPayload) > 0 {
+		if len(tsPayload) < 6 {
+			return nil, errors.New("illegal NIT transport stream entry")
+		}
 		entry := NITTransportEntry{}
 		entry.TransportStreamId = binary.BigEndian.Uint16(tsPayload[0:2])
 		entry.OriginalNetworkId = binary.BigEndian.Uint16(tsPayload[2:4])
 		entry.ServiceList = make(map[uint16]ServiceType)
 		tsDescLen := binary.BigEndian.Uint16(tsPayload[4:6]) & 0xfff
+		if int(tsDescLen)+6 > len(tsPayload) {
+			return nil, errors.New("illegal NIT transport descriptors length")
+		}
 		tsDescSlice := tsPayload[6 : 6+tsDescLen]
 		tsPayload = tsPayload[6+tsDescLen:]
 		tsDescReader := bytes.NewReader(tsDescSlice)
@@ -164,49 +222,58 @@ func parseNIT(payload []byte, _ *Decoder) (Frame, error) {
 					return nil, err
 				}
 			}
-			switch tagID {
-			case NetworkNameDescTagID:
-				name, err := b24.DecodeString(tagContent)
-				if err != nil {
-					return nil, err
-				}
+			switch {
+			case tagID == NetworkNameDescTagID:
+				name := text.decode(tagContent)
 				entry.NetworkName = name
-			case ServiceListDescTagID:
-				for len(tagContent) > 0 {
+			case tagID == ServiceListDescTagID:
+				for len(tagContent) >= 3 {
 					entry.ServiceList[binary.BigEndian.Uint16(tagContent[0:2])] = ServiceType(tagContent[2])
 					tagContent = tagContent[3:]
 				}
-			case TSInfoDescTagID:
+			case tagID == TSInfoDescTagID && len(tagContent) >= 2 && 2+int(tagContent[1]>>2) <= len(tagContent):
 				entry.TSInfo.RemoteControlKeyId = tagContent[0]
 				nameLen := tagContent[1] >> 2
-				entry.TSInfo.TSName, err = b24.DecodeString(tagContent[2 : 2+nameLen])
-				if err != nil {
-					return nil, err
+				entry.TSInfo.TSName = text.decode(tagContent[2 : 2+nameLen])
+			case tagID == SatelliteDescTagID && len(tagContent) >= 11:
+				entry.Satellite = parseSatelliteDeliverySystemDescriptor(tagContent)
+			case tagID == TerrestrialDescTagID && len(tagContent) >= 2:
+				entry.Terrestrial = &TerrestrialDeliverySystemDescriptor{}
+				areaCodeCombo := binary.BigEndian.Uint16(tagContent[0:2])
+				entry.Terrestrial.AreaCode = areaCodeCombo >> 4
+				entry.Terrestrial.GuardInterval = uint8(areaCodeCombo>>2) & 0b11
+				entry.Terrestrial.TransmissionMode = uint8(areaCodeCombo) & 0b11
+				tagContent = tagContent[2:]
+				for len(tagContent) >= 2 {
+					entry.Terrestrial.Frequencies = append(entry.Terrestrial.Frequencies, binary.BigEndian.Uint16(tagContent[0:2]))
+					tagContent = tagContent[2:]
 				}
-			case SatelliteDescTagID:
-				fallthrough
-			case 0xFA:
-				fallthrough
-			case 0xFB:
-				fallthrough
-			case 0xFD:
-				fallthrough
-			case 0xFE:
-				// ignore
+			case tagID == PartialReceptionDescTagID:
+				for len(tagContent) >= 2 {
+					entry.PartialReceptionServices = append(entry.PartialReceptionServices, binary.BigEndian.Uint16(tagContent[0:2]))
+					tagContent = tagContent[2:]
+				}
+			case tagID == DataComponentDescTagID && len(tagContent) >= 2:
+				entry.DataComponents = append(entry.DataComponents, DataComponentDescriptor{binary.BigEndian.Uint16(tagContent[0:2]), tagContent[2:]})
+			case tagID == SystemManagementDescTagID && len(tagContent) >= 2:
+				entry.SystemManagement = append(entry.SystemManagement, parseSystemManagementDescriptor(tagContent))
 			default:
-				log.Printf("NIT Entry tagID: %x, content: %v", tagID, tagContent)
+				entry.UnknownDescriptors = append(entry.UnknownDescriptors, Descriptor{tagID, tagContent})
 			}
 		}
 		frame.TransportStreams = append(frame.TransportStreams, entry)
 	}
+	frame.TextWarnings = text.warnings
 	return &frame, nil
 }
 
 func parseSDT(payload []byte, _ *Decoder) (Frame, error) {
-	if (payload[0] != 0x42 && payload[0] != 0x46) || payload[1]&0xf0 != 0xf0 {
+	if len(payload) < 15 || (TableID(payload[0]) != SDTActualTID && TableID(payload[0]) != SDTOtherTID) || payload[1]&0xf0 != 0xf0 {
 		return nil, errors.New("illegal SDT frame")
 	}
 	frame := SDTFrame{}
+	text := textDecoder{}
+	frame.TableID = TableID(payload[0])
 	frame.TransportStreamID = binary.BigEndian.Uint16(payload[3:5])
 	frame.Version = payload[5] & 0b111110 >> 1
 	frame.CurrentNext = payload[5]&1 == 1
@@ -217,12 +284,18 @@ func parseSDT(payload []byte, _ *Decoder) (Frame, error) {
 
 	payload = payload[11 : len(payload)-4]
 	for len(payload) > 0 {
+		if len(payload) < 5 {
+			return nil, errors.New("illegal SDT service entry")
+		}
 		entry := SDTFrameEntry{}
 		entry.ServiceID = binary.BigEndian.Uint16(payload[0:2])
 		entry.EITFlags = payload[2]
 		entry.RunningState = SDTRunningState(payload[3] >> 5)
 		entry.Scramble = payload[3]&0x10 == 0x10
 		descLen := binary.BigEndian.Uint16(payload[3:5]) & 0xfff
+		if int(descLen)+5 > len(payload) {
+			return nil, errors.New("illegal SDT service descriptors length")
+		}
 		descSlice := payload[5 : 5+descLen]
 		payload = payload[5+descLen:]
 		descReader := bytes.NewReader(descSlice)
@@ -235,22 +308,17 @@ func parseSDT(payload []byte, _ *Decoder) (Frame, error) {
 					return nil, err
 				}
 			}
-			switch tagID {
-			case ServiceDescTagID:
+			// descriptors too short to be parsed are left as unknown ones
+			switch {
+			case tagID == ServiceDescTagID && validServiceDescriptor(tagContent):
 				entry.Service = ServiceDescriptor{}
 				entry.Service.ServiceType = ServiceType(tagContent[0])
 				providerNameLen := tagContent[1]
-				entry.Service.ServiceProviderName, err = b24.DecodeString(tagContent[2 : 2+providerNameLen])
-				if err != nil {
-					return nil, err
-				}
+				entry.Service.ServiceProviderName = text.decode(tagContent[2 : 2+providerNameLen])
 				tagContent = tagContent[2+providerNameLen:]
 				nameLen := tagContent[0]
-				entry.Service.ServiceName, err = b24.DecodeString(tagContent[1 : 1+nameLen])
-				if err != nil {
-					return nil, err
-				}
-			case 0xCF:
+				entry.Service.ServiceName = text.decode(tagContent[1 : 1+nameLen])
+			case tagID == 0xCF && validLogoTransmissionDescriptor(tagContent):
 				// Logo
 				entry.Logo.LogoTransmissionType = tagContent[0]
 				switch entry.Logo.LogoTransmissionType {
@@ -261,24 +329,42 @@ func parseSDT(payload []byte, _ *Decoder) (Frame, error) {
 				case 0x02:
 					entry.Logo.LogoId = binary.BigEndian.Uint16(tagContent[1:3]) & 0x1ff
 				case 0x03:
-					str, err := b24.DecodeString(tagContent[1:])
-					if err != nil {
-						log.Printf("failed on parsing logo str: %v", err)
-						continue
-					}
+					str := text.decode(tagContent[1:])
 					entry.Logo.LogoStr = str
 				}
-			case 0xFE:
+			case tagID == 0xFE:
 				// ignore
 			default:
-				log.Printf("SDT tagID: %x, content: %v", tagID, tagContent)
+				entry.UnknownDescriptors = append(entry.UnknownDescriptors, Descriptor{tagID, tagContent})
 			}
 		}
 		frame.Entries = append(frame.Entries, entry)
 	}
+	frame.TextWarnings = text.warnings
 	return &frame, nil
 }
 
+// validServiceDescriptor reports whether the provider name and the service name are in the service descriptor
+func validServiceDescriptor(content []byte) bool {
+	if len(content) < 3 || 3+int(content[1]) > len(content) {
+		return false
+	}
+	return 3+int(content[1])+int(content[2+content[1]]) <= len(content)
+}
+
+// validLogoTransmissionDescriptor reports whether the logo transmission descriptor is long enough for its type
+func validLogoTransmissionDescriptor(content []byte) bool {
+	switch {
+	case len(content) < 1:
+		return false
+	case content[0] == 0x01:
+		return len(content) >= 7
+	case content[0] == 0x02:
+		return len(content) >= 3
+	}
+	return true
+}
+
 func parsePAT(payload []byte, d *Decoder) (Frame, error) {
 	if payload[0] != 0 || payload[1]&0xf0 != 0b10110000 {
 		return nil, errors.New("illegal PAT frame")
@@ -316,7 +402,7 @@ func parsePAT(payload []byte, d *Decoder) (Frame, error) {
 }
 
 func parsePMT(payload []byte, d *Decoder) (Frame, error) {
-	if payload[0] != 2 || payload[1]&0xf0 != 0b10110000 {
+	if len(payload) < 16 || payload[0] != 2 || payload[1]&0xf0 != 0b10110000 {
 		return nil, errors.New("illegal PMT frame")
 	}
 	frame := PMTFrame{}
@@ -328,6 +414,9 @@ func parsePMT(payload []byte, d *Decoder) (Frame, error) {
 	frame.LastSession = payload[7]
 	frame.PcrPID = binary.BigEndian.Uint16(payload[8:10]) & 0x1fff
 	programInfoLen := binary.BigEndian.Uint16(payload[10:12]) & 0xfff
+	if int(programInfoLen)+16 > len(payload) {
+		return nil, errors.New("illegal PMT program info length")
+	}
 	programInfoSlice := payload[12 : 12+programInfoLen]
 	payload = payload[12+programInfoLen : len(payload)-4]
 	programInfoReader := bytes.NewReader(programInfoSlice)
@@ -340,13 +429,25 @@ func parsePMT(payload []byte, d *Decoder) (Frame, error) {
 				return nil, err
 			}
 		}
-		log.Printf("PMT ProgInfo %x: %v", tagID, tagContent)
+		// descriptors too short to be parsed are left as unknown ones
+		switch {
+		case tagID == RestrictDescTagID && len(tagContent) >= 4:
+			frame.CA = append(frame.CA, parseCADescriptor(tagContent))
+		default:
+			frame.UnknownDescriptors = append(frame.UnknownDescriptors, Descriptor{tagID, tagContent})
+		}
 	}
 	for len(payload) > 0 {
+		if len(payload) < 5 {
+			return nil, errors.New("illegal PMT ES info")
+		}
 		esInfo := ESInfo{}
-		esInfo.StreamId = payload[0]
+		esInfo.StreamId = StreamType(payload[0])
 		esInfo.PID = binary.BigEndian.Uint16(payload[1:3]) & 0x1fff
 		esInfoLen := binary.BigEndian.Uint16(payload[3:5]) & 0xfff
+		if int(esInfoLen)+5 > len(payload) {
+			return nil, errors.New("illegal PMT ES info length")
+		}
 		esInfoDescSlice := payload[5 : 5+esInfoLen]
 		payload = payload[5+esInfoLen:]
 		esInfoDescReader := bytes.NewReader(esInfoDescSlice)
@@ -359,13 +460,71 @@ func parsePMT(payload []byte, d *Decoder) (Frame, error) {
 					return nil, err
 				}
 			}
-			log.Printf("PMT ESInfo %x: %v", tagID, tagContent)
+			switch {
+			case tagID == StreamIdentifierDescTagID && len(tagContent) >= 1:
+				esInfo.HasComponentTag = true
+				esInfo.ComponentTag = tagContent[0]
+			case tagID == ISO639LangDescTagID:
+				for len(tagContent) >= 4 {
+					esInfo.Languages = append(esInfo.Languages, ISO639LanguageDescriptor{string(tagContent[0:3]), tagContent[3]})
+					tagContent = tagContent[4:]
+				}
+			case tagID == RestrictDescTagID && len(tagContent) >= 4:
+				esInfo.CA = append(esInfo.CA, parseCADescriptor(tagContent))
+			case tagID == DataComponentDescTagID && len(tagContent) >= 2:
+				esInfo.DataComponent.DataComponentId = binary.BigEndian.Uint16(tagContent[0:2])
+				esInfo.DataComponent.AdditionalIdentification = tagContent[2:]
+			default:
+				esInfo.UnknownDescriptors = append(esInfo.UnknownDescriptors, Descriptor{tagID, tagContent})
+			}
 		}
 		frame.StreamList = append(frame.StreamList, esInfo)
 	}
 	return &frame, nil
 }
 
+func parseSatelliteDeliverySystemDescriptor(tagContent []byte) *SatelliteDeliverySystemDescriptor {
+	desc := SatelliteDeliverySystemDescriptor{}
+	desc.Frequency = uint32(parseBCD(tagContent[0:4], 8))
+	desc.OrbitalPosition = uint16(parseBCD(tagContent[4:6], 4))
+	desc.East = tagContent[6]&0x80 == 0x80
+	desc.Polarization = Polarization(tagContent[6] >> 5 & 0b11)
+	desc.Modulation = tagContent[6] & 0x1f
+	desc.SymbolRate = uint32(parseBCD(tagContent[7:11], 7))
+	desc.FECInner = tagContent[10] & 0xf
+	return &desc
+}
+
+func parseSystemManagementDescriptor(tagContent []byte) SystemManagementDescriptor {
+	return SystemManagementDescriptor{
+		BroadcastingFlag:         tagContent[0] >> 6,
+		BroadcastingIdentifier:   tagContent[0] & 0x3f,
+		AdditionalBroadcastingID: tagContent[1],
+		AdditionalIdentification: tagContent[2:],
+	}
+}
+
+// parseBCD reads the first digits of 4-bit BCD from raw
+func parseBCD(raw []byte, digits int) uint64 {
+	result := uint64(0)
+	for i := 0; i < digits; i++ {
+		nibble := raw[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		result = result*10 + uint64(nibble&0xf)
+	}
+	return result
+}
+
+func parseCADescriptor(tagContent []byte) CADescriptor {
+	return CADescriptor{
+		CASystemID:  binary.BigEndian.Uint16(tagContent[0:2]),
+		CAPID:       binary.BigEndian.Uint16(tagContent[2:4]) & 0x1fff,
+		PrivateData: tagContent[4:],
+	}
+}
+
 func parseMjd(raw []byte) time.Time {
 	if raw[0] == 0xff && raw[1] == 0xff && raw[2] == 0xff && raw[3] == 0xff && raw[4] == 0xff {
 		return time.UnixMicro(0) // N/A
@@ -380,15 +539,31 @@ func parseMjd(raw []byte) time.Time {
 	}
 	y := yp + k
 	m := mp - 1 - k*12
-	h := raw[2]
-	min := raw[3]
-	s := raw[4]
+	// hours, minutes and seconds are BCD
+	h := parseBCD(raw[2:3], 2)
+	min := parseBCD(raw[3:4], 2)
+	s := parseBCD(raw[4:5], 2)
 	loc, _ := time.LoadLocation("Asia/Tokyo")
 	return time.Date(y+1900, time.Month(m), d, int(h), int(min), int(s), 0, loc)
 }
 
+// parseDuration parses hhmmss in BCD, giving 0 for undefined duration of all 1 bits
 func parseDuration(raw []byte) time.Duration {
-	return time.Duration(raw[0])*time.Hour + time.Duration(raw[1])*time.Minute + time.Duration(raw[2])*time.Second
+	if raw[0] == 0xff && raw[1] == 0xff && raw[2] == 0xff {
+		return 0
+	}
+	return time.Duration(parseBCD(raw[0:1], 2))*time.Hour + time.Duration(parseBCD(raw[1:2], 2))*time.Minute + time.Duration(parseBCD(raw[2:3], 2))*time.Second
+}
+
+// textDecoder decodes SI text leniently and collects the warnings for the frame
+type textDecoder struct {
+	warnings []b24.Warning
+}
+
+func (t *textDecoder) decode(raw []byte) string {
+	decoded, warnings := b24.DecodeStringLenient(raw)
+	t.warnings = append(t.warnings, warnings...)
+	return decoded
 }
 
 func extractDescriptor(descriptorReader io.Reader) (uint8, []byte, error) {
@@ -495,12 +670,29 @@ func (d *Decoder) SeekNextPacket(expectedPID uint16, needPUSI bool) ([]byte, boo
 
 func (d *Decoder) readNextTSPacket() ([]byte, error) {
 	buf := make([]byte, PacketLength)
-	_, err := d.tsReader.Read(buf)
-	if err != nil {
+	// a pipe or a network stream may give a packet in pieces
+	_, err := io.ReadFull(d.tsReader, buf)
+	if err == io.EOF || err == io.ErrUnexpectedEOF {
 		return nil, err
 	}
+	if err != nil {
+		return nil, &ReadError{err}
+	}
+	d.packetOffset = d.offset
+	d.offset += int64(PacketLength)
 	if buf[0] != TsSyncCode {
+		if d.stats != nil {
+			d.stats.SyncErrors++
+		}
+		// read again from the next sync code candidate, so that the following packets are in alignment
+		if i := bytes.IndexByte(buf[1:], TsSyncCode); i >= 0 {
+			d.tsReader = io.MultiReader(bytes.NewReader(buf[1+i:]), d.tsReader)
+			d.offset -= int64(len(buf) - 1 - i)
+		}
 		return nil, errors.New("no valid TS sync code")
 	}
+	if d.stats != nil {
+		d.stats.observe(buf)
+	}
 	return buf, nil
 }
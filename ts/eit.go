@@ -6,54 +6,101 @@ import (
 	"errors"
 	"github.com/zlm2012/wildwrap/b24"
 	"io"
-	"log"
 	"time"
 )
 
 type EITShortEventDescriptor struct {
-	LangCode  string
-	EventName string
-	Text      string
+	LangCode  string `json:"lang_code"`
+	EventName string `json:"event_name"`
+	Text      string `json:"text"`
 }
 
 type EITExtendedEventEntry struct {
-	Name        string
-	Description string
+	Name        string `json:"name"`
+	Description string `json:"description"`
 }
 
 type EITExtendedEventDescriptor struct {
-	LangCode    string
-	Entries     []EITExtendedEventEntry
-	Description string
+	LangCode    string                  `json:"lang_code"`
+	Entries     []EITExtendedEventEntry `json:"entries"`
+	Description string                  `json:"description"`
 }
 
 type EITContentDescriptorEntry struct {
-	SubGenre   SubGenre
-	UserDefine uint8
+	SubGenre   SubGenre `json:"sub_genre"`
+	UserDefine uint8    `json:"user_define"`
+}
+
+// ProgramAttribute returns the programme attribute carried in user nibbles of the extension genre
+func (e EITContentDescriptorEntry) ProgramAttribute() (ProgramAttribute, bool) {
+	if e.SubGenre != ExtensionProgramAttribute {
+		return 0, false
+	}
+	return ProgramAttribute(e.UserDefine), true
+}
+
+// CSGenre returns the CS genre carried in user nibbles of the extension genre
+func (e EITContentDescriptorEntry) CSGenre() (CSGenre, bool) {
+	if e.SubGenre != ExtensionCS {
+		return 0, false
+	}
+	return CSGenre(e.UserDefine), true
+}
+
+func (e EITContentDescriptorEntry) IsAnime() bool {
+	if csGenre, ok := e.CSGenre(); ok {
+		return csGenre.IsAnime()
+	}
+	return e.SubGenre.IsAnime()
+}
+
+func (e EITContentDescriptorEntry) String() string {
+	if attr, ok := e.ProgramAttribute(); ok {
+		return attr.String()
+	}
+	if csGenre, ok := e.CSGenre(); ok {
+		return csGenre.String()
+	}
+	return e.SubGenre.String()
 }
 
 type EITContentDescriptor struct {
-	Entries []EITContentDescriptorEntry
+	Entries []EITContentDescriptorEntry `json:"entries"`
+}
+
+// IsAnime reports whether any of the genres is animation or tokusatsu, see SubGenre.IsAnime
+func (d EITContentDescriptor) IsAnime() bool {
+	for _, entry := range d.Entries {
+		if entry.IsAnime() {
+			return true
+		}
+	}
+	return false
 }
 
 type EITFrame struct {
-	TableID           uint8
-	ServiceID         uint16
-	TSID              uint16
-	OriginalNetworkID uint16
-	Entries           []EITFrameEntry
+	TableID           TableID `json:"table_id"`
+	ServiceID         uint16  `json:"service_id"`
+	TSID              uint16  `json:"tsid"`
+	OriginalNetworkID uint16  `json:"original_network_id"`
+	// SectionNumber is 0 for the present event and 1 for the following event in EIT p/f
+	SectionNumber uint8           `json:"section_number"`
+	Entries       []EITFrameEntry `json:"entries"`
+	TextWarnings  []b24.Warning   `json:"text_warnings"`
 }
 
 type EITFrameEntry struct {
-	EventID            uint16
-	StartTime          time.Time
-	Duration           time.Duration
-	RunningState       SDTRunningState
-	FreeCA             bool
-	DualMono           bool
-	Contents           EITContentDescriptor
-	ShortDescriptor    EITShortEventDescriptor
-	ExtendedDescriptor []EITExtendedEventDescriptor
+	EventID            uint16                       `json:"event_id"`
+	StartTime          time.Time                    `json:"start_time"`
+	Duration           time.Duration                `json:"duration"`
+	RunningState       SDTRunningState              `json:"running_state"`
+	FreeCA             bool                         `json:"free_ca"`
+	DualMono           bool                         `json:"dual_mono"`
+	Contents           EITContentDescriptor         `json:"contents"`
+	ShortDescriptor    EITShortEventDescriptor      `json:"short_descriptor"`
+	ExtendedDescriptor []EITExtendedEventDescriptor `json:"extended_descriptor"`
+	// UnknownDescriptors are the descriptors of the event not parsed, e.g. component descriptors
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
 }
 
 func (f *EITFrame) IsParsed() bool {
@@ -64,7 +111,7 @@ func (f *EITFrame) GetType() string {
 	return "EIT"
 }
 
-func parseEITEntry(entryPayload []byte) (*EITFrameEntry, int, error) {
+func parseEITEntry(entryPayload []byte, text *textDecoder) (*EITFrameEntry, int, error) {
 	entry := EITFrameEntry{}
 	entry.EventID = binary.BigEndian.Uint16(entryPayload[0:2])
 	entry.StartTime = parseMjd(entryPayload[2:7])
@@ -86,14 +133,8 @@ func parseEITEntry(entryPayload []byte) (*EITFrameEntry, int, error) {
 		switch tagID {
 		case ShortEventDescTagID:
 			entry.ShortDescriptor.LangCode = string(tagContent[0:3])
-			entry.ShortDescriptor.EventName, err = b24.DecodeString(tagContent[4 : 4+tagContent[3]])
-			if err != nil {
-				return nil, 12 + entryLen, err
-			}
-			entry.ShortDescriptor.Text, err = b24.DecodeString(tagContent[4+tagContent[3]+1:])
-			if err != nil {
-				return nil, 12 + entryLen, err
-			}
+			entry.ShortDescriptor.EventName = text.decode(tagContent[4 : 4+tagContent[3]])
+			entry.ShortDescriptor.Text = text.decode(tagContent[4+tagContent[3]+1:])
 		case ContentDescTagID:
 			genreCount := len(tagContent) / 2
 			entries := make([]EITContentDescriptorEntry, genreCount)
@@ -113,49 +154,46 @@ func parseEITEntry(entryPayload []byte) (*EITFrameEntry, int, error) {
 			extDesc.Entries = make([]EITExtendedEventEntry, 0)
 			for len(itemRaw) != 0 {
 				nameLen := itemRaw[0]
-				name, err := b24.DecodeString(itemRaw[1 : 1+nameLen])
-				if err != nil {
-					return nil, 12 + entryLen, err
-				}
+				name := text.decode(itemRaw[1 : 1+nameLen])
 				descLen := itemRaw[1+nameLen]
-				desc, err := b24.DecodeString(itemRaw[2+nameLen : 2+nameLen+descLen])
-				if err != nil {
-					return nil, 12 + entryLen, err
-				}
+				desc := text.decode(itemRaw[2+nameLen : 2+nameLen+descLen])
 				extDesc.Entries = append(extDesc.Entries, EITExtendedEventEntry{name, desc})
 				itemRaw = itemRaw[2+nameLen+descLen:]
 			}
 			descLen := tagContent[5+itemsLen]
-			extDesc.Description, err = b24.DecodeString(tagContent[6+itemsLen : 6+itemsLen+descLen])
-			if err != nil {
-				return nil, 12 + entryLen, err
-			}
+			extDesc.Description = text.decode(tagContent[6+itemsLen : 6+itemsLen+descLen])
 			if entry.ExtendedDescriptor == nil {
 				entry.ExtendedDescriptor = []EITExtendedEventDescriptor{extDesc}
 			} else {
 				entry.ExtendedDescriptor = append(entry.ExtendedDescriptor, extDesc)
 			}
 		default:
-			log.Printf("tag %d %v", tagID, tagContent)
+			entry.UnknownDescriptors = append(entry.UnknownDescriptors, Descriptor{tagID, tagContent})
 		}
 	}
 }
 
 func parseEIT(entryPayload []byte, _ *Decoder) (Frame, error) {
-	if entryPayload[1]&0xf0 != 0xf0 {
+	if len(entryPayload) < 18 || entryPayload[1]&0xf0 != 0xf0 {
 		return nil, errors.New("illegal EIT frame")
 	}
 	eitFrame := EITFrame{}
-	eitFrame.TableID = entryPayload[0]
+	eitFrame.TableID = TableID(entryPayload[0])
+	eitFrame.ServiceID = binary.BigEndian.Uint16(entryPayload[3:5])
+	eitFrame.SectionNumber = entryPayload[6]
+	eitFrame.TSID = binary.BigEndian.Uint16(entryPayload[8:10])
+	eitFrame.OriginalNetworkID = binary.BigEndian.Uint16(entryPayload[10:12])
 	eitFrame.Entries = make([]EITFrameEntry, 0)
 	remaining := entryPayload[14 : len(entryPayload)-4]
+	text := textDecoder{}
 	for len(remaining) > 0 {
-		entry, parsedLen, err := parseEITEntry(remaining)
+		entry, parsedLen, err := parseEITEntry(remaining, &text)
 		if err != nil {
 			return nil, err
 		}
 		eitFrame.Entries = append(eitFrame.Entries, *entry)
 		remaining = remaining[parsedLen:]
 	}
+	eitFrame.TextWarnings = text.warnings
 	return &eitFrame, nil
 }
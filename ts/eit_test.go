@@ -0,0 +1,27 @@
+package ts
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseEITTimes(t *testing.T) {
+	eit := []byte{byte(EITCurrentStreamTID), 0xf0, 0, 0x04, 0x00, 0xc1, 0, 1, 0x7f, 0xe0, 0x7f, 0xe0, 1, byte(EITCurrentStreamTID),
+		// 2018-09-15 12:34:56 for 1h30m
+		0x00, 0x01, 0xe4, 0x08, 0x12, 0x34, 0x56, 0x01, 0x30, 0x00, 0x80, 0,
+		// undefined duration
+		0x00, 0x02, 0xe4, 0x08, 0x23, 0x59, 0x00, 0xff, 0xff, 0xff, 0x20, 0,
+		0, 0, 0, 0}
+	frame, err := parseEIT(eit, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := frame.(*EITFrame).Entries
+	jst := time.FixedZone("JST", 9*60*60)
+	if len(entries) != 2 || !entries[0].StartTime.Equal(time.Date(2018, 9, 15, 12, 34, 56, 0, jst)) || entries[0].Duration != 90*time.Minute {
+		t.Fatalf("unexpected entries: %+v", entries)
+	}
+	if !entries[1].StartTime.Equal(time.Date(2018, 9, 15, 23, 59, 0, 0, jst)) || entries[1].Duration != 0 {
+		t.Fatalf("unexpected entry of undefined duration: %+v", entries[1])
+	}
+}
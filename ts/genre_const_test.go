@@ -0,0 +1,43 @@
+package ts
+
+import "testing"
+
+func TestGenreNames(t *testing.T) {
+	if AnimeJapanese.String() != "Animation/Special Effects - Japanese Anime" || AnimeJapanese.JapaneseName() != "アニメ／特撮 - 国内アニメ" {
+		t.Fatalf("unexpected names: %s, %s", AnimeJapanese.String(), AnimeJapanese.JapaneseName())
+	}
+	if genre, ok := ParseGenre("ドラマ"); !ok || genre != Drama {
+		t.Fatalf("unexpected genre: %v", genre)
+	}
+	if subGenre, ok := ParseSubGenre("国内アニメ"); !ok || subGenre != AnimeJapanese {
+		t.Fatalf("unexpected sub genre: %v", subGenre)
+	}
+	if _, ok := ParseSubGenre("その他"); ok {
+		t.Fatal("ambiguous sub genre should not be parsed")
+	}
+	if subGenre, ok := ParseSubGenre("Sports - Others"); !ok || subGenre != SportsOthers {
+		t.Fatalf("unexpected sub genre: %v", subGenre)
+	}
+	if !AnimeJapanese.Genre().IsAnime() || !AnimeJapanese.IsAnime() || !MoviesAnime.IsAnime() || Drama.IsAnime() {
+		t.Fatal("unexpected anime classification")
+	}
+	// tokusatsu is in the anime genre, and both helpers agree on it
+	if !SpecialEffects.Genre().IsAnime() || !SpecialEffects.IsAnime() {
+		t.Fatal("tokusatsu should be classified as anime by both genre and sub genre")
+	}
+	tokusatsu := EITContentDescriptor{[]EITContentDescriptorEntry{{SpecialEffects, 0}}}
+	if !tokusatsu.IsAnime() {
+		t.Fatal("tokusatsu event should be classified as anime")
+	}
+	cs := EITContentDescriptorEntry{ExtensionCS, uint8(CSMoviesJapaneseAnimation)}
+	if !cs.IsAnime() || cs.String() != "Japanese Movies (CS) - Animation" {
+		t.Fatalf("unexpected cs genre: %s", cs.String())
+	}
+	attr := EITContentDescriptorEntry{ExtensionProgramAttribute, uint8(ProgramAttributeMayBeExtended)}
+	if attr.String() != "May Be Extended" {
+		t.Fatalf("unexpected programme attribute: %s", attr.String())
+	}
+	if !ServiceTypeDigitalTV.IsTV() || !ServiceTypeDigitalAudio.IsRadio() || !ServiceTypeData.IsData() || ServiceTypeEngineering.IsTV() {
+		t.Fatal("unexpected service type classification")
+	}
+}
@@ -5,3 +5,5 @@ go 1.17
 require github.com/zlm2012/wildwrap/b24 v0.0.0-20220323164031-7a27ae70bbd3
 
 require golang.org/x/text v0.3.7 // indirect
+
+replace github.com/zlm2012/wildwrap/b24 => ../b24
@@ -0,0 +1,402 @@
+package ts
+
+// JSON form of frames and descriptors
+//
+// Fields are in snake_case of the Go field names, as given by the json tags.
+// Enums are strings of their English names given by String, e.g. "Digital TV" for ServiceType,
+// or "0x" and the value in hex if it has no name of its own.
+// Times are in RFC 3339, a profile of ISO 8601, with the offset of JST, e.g. "2022-03-01T12:34:56+09:00".
+// Durations are strings of time.Duration, e.g. "1h30m0s".
+// Raw descriptors not parsed have data in hex, and other byte strings are in base64.
+// FrameJSON wraps any frame with its type, to be unmarshaled without knowing the type in advance.
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// enumNames maps the names of a uint8 enum to the values both ways
+type enumNames struct {
+	once   sync.Once
+	name   func(uint8) string
+	values map[string]uint8
+	// ambiguous are the names shared by several values, which are given in hex instead
+	ambiguous map[string]bool
+}
+
+func newEnumNames(name func(uint8) string) *enumNames {
+	return &enumNames{name: name}
+}
+
+func (e *enumNames) build() {
+	e.values = map[string]uint8{}
+	e.ambiguous = map[string]bool{}
+	for i := 0; i < 256; i++ {
+		name := e.name(uint8(i))
+		if _, ok := e.values[name]; ok {
+			e.ambiguous[name] = true
+			continue
+		}
+		e.values[name] = uint8(i)
+	}
+}
+
+func (e *enumNames) marshal(v uint8) ([]byte, error) {
+	e.once.Do(e.build)
+	name := e.name(v)
+	if e.ambiguous[name] {
+		name = "0x" + strconv.FormatUint(uint64(v), 16)
+	}
+	return []byte(name), nil
+}
+
+func (e *enumNames) unmarshal(text []byte) (uint8, error) {
+	e.once.Do(e.build)
+	name := string(text)
+	if v, ok := e.values[name]; ok && !e.ambiguous[name] {
+		return v, nil
+	}
+	if v, err := strconv.ParseUint(name, 0, 8); err == nil {
+		return uint8(v), nil
+	}
+	return 0, errors.New(fmt.Sprintf("unknown enum name %q", name))
+}
+
+var (
+	serviceTypeEnum      = newEnumNames(func(v uint8) string { return ServiceType(v).String() })
+	runningStateEnum     = newEnumNames(func(v uint8) string { return SDTRunningState(v).String() })
+	polarizationEnum     = newEnumNames(func(v uint8) string { return Polarization(v).String() })
+	genreEnum            = newEnumNames(func(v uint8) string { return Genre(v).String() })
+	subGenreEnum         = newEnumNames(func(v uint8) string { return SubGenre(v).String() })
+	programAttributeEnum = newEnumNames(func(v uint8) string { return ProgramAttribute(v).String() })
+	csGenreEnum          = newEnumNames(func(v uint8) string { return CSGenre(v).String() })
+	tableIDEnum          = newEnumNames(func(v uint8) string { return TableID(v).String() })
+	streamTypeEnum       = newEnumNames(func(v uint8) string { return StreamType(v).String() })
+)
+
+func (v ServiceType) MarshalText() ([]byte, error) {
+	return serviceTypeEnum.marshal(uint8(v))
+}
+
+func (v *ServiceType) UnmarshalText(text []byte) error {
+	parsed, err := serviceTypeEnum.unmarshal(text)
+	*v = ServiceType(parsed)
+	return err
+}
+
+func (v SDTRunningState) MarshalText() ([]byte, error) {
+	return runningStateEnum.marshal(uint8(v))
+}
+
+func (v *SDTRunningState) UnmarshalText(text []byte) error {
+	parsed, err := runningStateEnum.unmarshal(text)
+	*v = SDTRunningState(parsed)
+	return err
+}
+
+func (p Polarization) MarshalText() ([]byte, error) {
+	return polarizationEnum.marshal(uint8(p))
+}
+
+func (p *Polarization) UnmarshalText(text []byte) error {
+	parsed, err := polarizationEnum.unmarshal(text)
+	*p = Polarization(parsed)
+	return err
+}
+
+func (v Genre) MarshalText() ([]byte, error) {
+	return genreEnum.marshal(uint8(v))
+}
+
+func (v *Genre) UnmarshalText(text []byte) error {
+	parsed, err := genreEnum.unmarshal(text)
+	*v = Genre(parsed)
+	return err
+}
+
+func (v SubGenre) MarshalText() ([]byte, error) {
+	return subGenreEnum.marshal(uint8(v))
+}
+
+func (v *SubGenre) UnmarshalText(text []byte) error {
+	parsed, err := subGenreEnum.unmarshal(text)
+	*v = SubGenre(parsed)
+	return err
+}
+
+func (v ProgramAttribute) MarshalText() ([]byte, error) {
+	return programAttributeEnum.marshal(uint8(v))
+}
+
+func (v *ProgramAttribute) UnmarshalText(text []byte) error {
+	parsed, err := programAttributeEnum.unmarshal(text)
+	*v = ProgramAttribute(parsed)
+	return err
+}
+
+func (v CSGenre) MarshalText() ([]byte, error) {
+	return csGenreEnum.marshal(uint8(v))
+}
+
+func (v *CSGenre) UnmarshalText(text []byte) error {
+	parsed, err := csGenreEnum.unmarshal(text)
+	*v = CSGenre(parsed)
+	return err
+}
+
+func (v TableID) MarshalText() ([]byte, error) {
+	return tableIDEnum.marshal(uint8(v))
+}
+
+func (v *TableID) UnmarshalText(text []byte) error {
+	parsed, err := tableIDEnum.unmarshal(text)
+	*v = TableID(parsed)
+	return err
+}
+
+func (v StreamType) MarshalText() ([]byte, error) {
+	return streamTypeEnum.marshal(uint8(v))
+}
+
+func (v *StreamType) UnmarshalText(text []byte) error {
+	parsed, err := streamTypeEnum.unmarshal(text)
+	*v = StreamType(parsed)
+	return err
+}
+
+type jsonDescriptor struct {
+	Tag  uint8  `json:"tag"`
+	Data string `json:"data"`
+}
+
+// MarshalJSON gives the content in hex instead of base64
+func (d Descriptor) MarshalJSON() ([]byte, error) {
+	return json.Marshal(jsonDescriptor{d.Tag, hex.EncodeToString(d.Data)})
+}
+
+func (d *Descriptor) UnmarshalJSON(data []byte) error {
+	var raw jsonDescriptor
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	content, err := hex.DecodeString(raw.Data)
+	if err != nil {
+		return err
+	}
+	*d = Descriptor{raw.Tag, content}
+	return nil
+}
+
+// parseJSONDuration parses a duration string, leaving 0 for an absent one
+func parseJSONDuration(s string) (time.Duration, error) {
+	if s == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// MarshalJSON gives TransportStreamID as a number, omitted if the PAT has none
+func (f PATFrame) MarshalJSON() ([]byte, error) {
+	type plain PATFrame
+	var tsid *uint16
+	if len(f.TransportStreamID) == 2 {
+		v := uint16(f.TransportStreamID[0])<<8 | uint16(f.TransportStreamID[1])
+		tsid = &v
+	}
+	return json.Marshal(struct {
+		plain
+		TransportStreamID *uint16 `json:"transport_stream_id,omitempty"`
+	}{plain(f), tsid})
+}
+
+func (f *PATFrame) UnmarshalJSON(data []byte) error {
+	type plain PATFrame
+	aux := struct {
+		*plain
+		TransportStreamID *uint16 `json:"transport_stream_id"`
+	}{plain: (*plain)(f)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	f.TransportStreamID = nil
+	if tsid := aux.TransportStreamID; tsid != nil {
+		f.TransportStreamID = []byte{byte(*tsid >> 8), byte(*tsid)}
+	}
+	return nil
+}
+
+func (e EITFrameEntry) MarshalJSON() ([]byte, error) {
+	type plain EITFrameEntry
+	return json.Marshal(struct {
+		plain
+		Duration string `json:"duration"`
+	}{plain(e), e.Duration.String()})
+}
+
+func (e *EITFrameEntry) UnmarshalJSON(data []byte) error {
+	type plain EITFrameEntry
+	aux := struct {
+		*plain
+		Duration string `json:"duration"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var err error
+	e.Duration, err = parseJSONDuration(aux.Duration)
+	return err
+}
+
+func (g EITScheduleCycleGroup) MarshalJSON() ([]byte, error) {
+	type plain EITScheduleCycleGroup
+	return json.Marshal(struct {
+		plain
+		Cycle string `json:"cycle"`
+	}{plain(g), g.Cycle.String()})
+}
+
+func (g *EITScheduleCycleGroup) UnmarshalJSON(data []byte) error {
+	type plain EITScheduleCycleGroup
+	aux := struct {
+		*plain
+		Cycle string `json:"cycle"`
+	}{plain: (*plain)(g)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var err error
+	g.Cycle, err = parseJSONDuration(aux.Cycle)
+	return err
+}
+
+func (p EITScheduleParameter) MarshalJSON() ([]byte, error) {
+	type plain EITScheduleParameter
+	return json.Marshal(struct {
+		plain
+		BaseCycle string `json:"base_cycle"`
+	}{plain(p), p.BaseCycle.String()})
+}
+
+func (p *EITScheduleParameter) UnmarshalJSON(data []byte) error {
+	type plain EITScheduleParameter
+	aux := struct {
+		*plain
+		BaseCycle string `json:"base_cycle"`
+	}{plain: (*plain)(p)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var err error
+	p.BaseCycle, err = parseJSONDuration(aux.BaseCycle)
+	return err
+}
+
+func (e SIParameterEntry) MarshalJSON() ([]byte, error) {
+	type plain SIParameterEntry
+	return json.Marshal(struct {
+		plain
+		TableCycle string `json:"table_cycle"`
+	}{plain(e), e.TableCycle.String()})
+}
+
+func (e *SIParameterEntry) UnmarshalJSON(data []byte) error {
+	type plain SIParameterEntry
+	aux := struct {
+		*plain
+		TableCycle string `json:"table_cycle"`
+	}{plain: (*plain)(e)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	var err error
+	e.TableCycle, err = parseJSONDuration(aux.TableCycle)
+	return err
+}
+
+// FrameJSON is the JSON form of any frame, {"type": "EIT", "frame": {...}} with the type by GetType
+type FrameJSON struct {
+	Frame Frame
+}
+
+// newFrameOfType gives an empty frame to unmarshal into, by the type given by GetType
+func newFrameOfType(frameType string) (Frame, error) {
+	switch frameType {
+	case "PAT":
+		return &PATFrame{}, nil
+	case "PMT":
+		return &PMTFrame{}, nil
+	case "NIT":
+		return &NITFrame{}, nil
+	case "SDT":
+		return &SDTFrame{}, nil
+	case "EIT":
+		return &EITFrame{}, nil
+	case "TDT", "TOT":
+		return &TOTFrame{}, nil
+	case "BIT":
+		return &BITFrame{}, nil
+	case "CDT":
+		return &CDTFrame{}, nil
+	case "PES":
+		return &PESFrame{}, nil
+	case "generic":
+		return &GeneralFrame{}, nil
+	}
+	return nil, errors.New("unknown frame type: " + frameType)
+}
+
+type frameJSON struct {
+	Type  string          `json:"type"`
+	Frame json.RawMessage `json:"frame"`
+}
+
+func (f FrameJSON) MarshalJSON() ([]byte, error) {
+	if f.Frame == nil {
+		return []byte("null"), nil
+	}
+	frame, err := json.Marshal(f.Frame)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(frameJSON{f.Frame.GetType(), frame})
+}
+
+func (f *FrameJSON) UnmarshalJSON(data []byte) error {
+	if strings.TrimSpace(string(data)) == "null" {
+		f.Frame = nil
+		return nil
+	}
+	var raw frameJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	frame, err := newFrameOfType(raw.Type)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(raw.Frame, frame); err != nil {
+		return err
+	}
+	f.Frame = frame
+	return nil
+}
+
+// MarshalFrame gives the JSON form of the frame with its type
+func MarshalFrame(frame Frame) ([]byte, error) {
+	return json.Marshal(FrameJSON{frame})
+}
+
+// UnmarshalFrame parses the JSON form given by MarshalFrame
+func UnmarshalFrame(data []byte) (Frame, error) {
+	var f FrameJSON
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, err
+	}
+	return f.Frame, nil
+}
@@ -0,0 +1,101 @@
+package ts
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+func TestEnumJSONRoundTrip(t *testing.T) {
+	for i := 0; i < 256; i++ {
+		values := []interface{}{ServiceType(i), SDTRunningState(i), Polarization(i), Genre(i), SubGenre(i), ProgramAttribute(i), CSGenre(i), TableID(i), StreamType(i)}
+		for _, value := range values {
+			raw, err := json.Marshal(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			parsed := reflect.New(reflect.TypeOf(value))
+			if err := json.Unmarshal(raw, parsed.Interface()); err != nil {
+				t.Fatalf("%T %s: %v", value, raw, err)
+			}
+			if parsed.Elem().Interface() != value {
+				t.Fatalf("%T %d is unmarshaled as %v from %s", value, i, parsed.Elem().Interface(), raw)
+			}
+		}
+	}
+	raw, _ := json.Marshal(map[uint16]ServiceType{1024: ServiceTypeDigitalTV, 1025: 0x99})
+	if string(raw) != `{"1024":"Digital TV","1025":"0x99"}` {
+		t.Fatalf("unexpected JSON: %s", raw)
+	}
+}
+
+func TestFrameJSONRoundTrip(t *testing.T) {
+	start := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	frames := []Frame{
+		&PATFrame{TransportStreamID: []byte{0x7f, 0xe0}, Version: 3, CurrentNext: true, NetworkPID: 0x10, SidPidMap: map[uint16]uint16{1024: 0x1f0}},
+		&PATFrame{Version: 1, SidPidMap: map[uint16]uint16{}},
+		&PMTFrame{ServiceID: 1024, CurrentNext: true, PcrPID: 0x1ff, CA: []CADescriptor{{CASystemID: 5, CAPID: 0x901, PrivateData: []byte{1}}},
+			StreamList: []ESInfo{{StreamId: StreamTypeH264, PID: 0x111, HasComponentTag: true, Languages: []ISO639LanguageDescriptor{{"jpn", 0}},
+				UnknownDescriptors: []Descriptor{{0xc8, []byte{0x01}}}}},
+		},
+		&NITFrame{TableID: NITActualTID, NetworkID: 4, NetworkName: "BS", TransportStreams: []NITTransportEntry{{
+			TransportStreamId: 0x4010, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: ServiceTypeDigitalTV},
+			Satellite: &SatelliteDeliverySystemDescriptor{Frequency: 1172748, Polarization: PolarizationCircularRight},
+		}}},
+		&SDTFrame{TableID: SDTActualTID, Entries: []SDTFrameEntry{{ServiceID: 101, RunningState: RSRunning,
+			Service: ServiceDescriptor{ServiceTypeDigitalTV, "NHK", "ＮＨＫ　ＢＳ１"}}},
+			TextWarnings: []b24.Warning{{Offset: 3, Message: "unknown gaiji"}}},
+		&EITFrame{TableID: EITCurrentStreamTID, ServiceID: 101, Entries: []EITFrameEntry{{
+			EventID: 1, StartTime: start, Duration: 90 * time.Minute, RunningState: RSRunning,
+			Contents:        EITContentDescriptor{[]EITContentDescriptorEntry{{AnimeJapanese, 0}, {ExtensionProgramAttribute, uint8(ProgramAttributeMayBeExtended)}}},
+			ShortDescriptor: EITShortEventDescriptor{"jpn", "番組", "説明"},
+		}}},
+		&TOTFrame{TOTTID, start},
+		&BITFrame{TableID: BITTID, SIParameters: []SIParameterDescriptor{{UpdateTime: start, Entries: []SIParameterEntry{
+			{TableID: NITActualTID, TableCycle: 10 * time.Second},
+			{TableID: 0x50, Schedule: &EITScheduleParameter{ScheduleRange: 8, BaseCycle: time.Minute, CycleGroups: []EITScheduleCycleGroup{{1, 2 * time.Minute}}}},
+		}}}},
+		&CDTFrame{TableID: CDTTID, DataModule: []byte{0x89, 'P', 'N', 'G'}},
+		&PESFrame{PID: 0x130, StreamID: PrivateStream1ID, HasPTS: true, PTS: 1 << 32, Payload: []byte{0x80}},
+	}
+	for _, frame := range frames {
+		raw, err := MarshalFrame(frame)
+		if err != nil {
+			t.Fatal(err)
+		}
+		parsed, err := UnmarshalFrame(raw)
+		if err != nil {
+			t.Fatalf("%s: %v", raw, err)
+		}
+		if !reflect.DeepEqual(parsed, frame) {
+			t.Fatalf("%s is unmarshaled as %+v", raw, parsed)
+		}
+	}
+
+	raw, _ := MarshalFrame(frames[0])
+	if !strings.Contains(string(raw), `"type":"PAT"`) || !strings.Contains(string(raw), `"transport_stream_id":32736`) {
+		t.Fatalf("unexpected PAT JSON: %s", raw)
+	}
+	raw, _ = MarshalFrame(frames[1])
+	if strings.Contains(string(raw), "transport_stream_id") {
+		t.Fatalf("unexpected PAT JSON without TSID: %s", raw)
+	}
+	raw, _ = MarshalFrame(frames[2])
+	if !strings.Contains(string(raw), `"stream_type":"H.264"`) {
+		t.Fatalf("unexpected PMT JSON: %s", raw)
+	}
+	raw, _ = MarshalFrame(frames[5])
+	for _, expected := range []string{`"table_id":"EIT p/f actual"`, `"start_time":"2022-03-01T12:00:00Z"`, `"duration":"1h30m0s"`, `"running_state":"running"`, `"sub_genre":"Animation/Special Effects - Japanese Anime"`} {
+		if !strings.Contains(string(raw), expected) {
+			t.Fatalf("%s is not found in EIT JSON: %s", expected, raw)
+		}
+	}
+	jst, _ := json.Marshal(parseMjd([]byte{0xe4, 0x08, 0x12, 0x34, 0x56}))
+	if !strings.HasSuffix(string(jst), `T12:34:56+09:00"`) {
+		t.Fatalf("unexpected JST time: %s", jst)
+	}
+}
@@ -0,0 +1,150 @@
+package ts
+
+import (
+	"encoding/binary"
+	"errors"
+	"log"
+)
+
+const (
+	PrivateStream1ID uint8 = 0xBD
+	PaddingStreamID  uint8 = 0xBE
+	PrivateStream2ID uint8 = 0xBF
+
+	// PTSClock is the frequency of PTS and DTS
+	PTSClock uint64 = 90000
+	ptsMask  uint64 = 1<<33 - 1
+)
+
+// PESFrame is a PES packet on a PID watched by WatchPES
+type PESFrame struct {
+	PID      uint16 `json:"pid"`
+	StreamID uint8  `json:"stream_id"`
+	HasPTS   bool   `json:"has_pts"`
+	PTS      uint64 `json:"pts"`
+	HasDTS   bool   `json:"has_dts"`
+	DTS      uint64 `json:"dts"`
+	// Payload is PES packet data bytes, or the part of them in the first TS packet if the PID is watched header only
+	Payload []byte `json:"payload"`
+}
+
+func (f *PESFrame) IsParsed() bool {
+	return true
+}
+
+func (f *PESFrame) GetType() string {
+	return "PES"
+}
+
+// PTSDiff gives pts - base in 90kHz, taking the wrap around of 33 bits into account
+func PTSDiff(pts, base uint64) int64 {
+	diff := int64((pts - base) & ptsMask)
+	if diff >= 1<<32 {
+		diff -= 1 << 33
+	}
+	return diff
+}
+
+type pesBuffer struct {
+	buf         []byte
+	lastCounter uint8
+	headerOnly  bool
+	offset      int64
+}
+
+// WatchPES makes ParseNext give PES packets on the PID as PESFrame.
+// With headerOnly, each frame is given as soon as the first TS packet of the PES packet comes,
+// which is enough to get PTS e.g. of video.
+func (d *Decoder) WatchPES(PID uint16, headerOnly bool) {
+	d.pesBuffer[PID] = &pesBuffer{headerOnly: headerOnly}
+}
+
+// UnwatchPES stops giving PES packets on the PID
+func (d *Decoder) UnwatchPES(PID uint16) {
+	delete(d.pesBuffer, PID)
+}
+
+// pushPES adds the TS packet to the PES buffer of the PID, and gives the PES packet if it is completed
+func (d *Decoder) pushPES(PID uint16, pesBuf *pesBuffer, packet []byte, isPUSI bool) (*PESFrame, error) {
+	if PayloadFlagMask&packet[3] != PayloadFlagMask {
+		return nil, nil
+	}
+	counter := packet[3] & CounterMask
+	payload := getPayload(packet)
+	var completed []byte
+	completedOffset := pesBuf.offset
+	if isPUSI {
+		completed = pesBuf.buf
+		pesBuf.buf = append([]byte(nil), payload...)
+		pesBuf.lastCounter = counter
+		pesBuf.offset = d.packetOffset
+		if pesBuf.headerOnly {
+			completed = pesBuf.buf
+			completedOffset = pesBuf.offset
+			pesBuf.buf = nil
+		}
+	} else if pesBuf.buf == nil {
+		return nil, nil
+	} else if pesBuf.lastCounter == counter {
+		return nil, nil
+	} else if (pesBuf.lastCounter+1)&CounterMask == counter {
+		pesBuf.lastCounter = counter
+		pesBuf.buf = append(pesBuf.buf, payload...)
+	} else {
+		log.Printf("counter is not in continuity for PES PID %d", PID)
+		pesBuf.buf = nil
+		return nil, nil
+	}
+	if completed == nil && len(pesBuf.buf) >= 6 {
+		// PES packet with the length is completed without waiting for the next one
+		if pesLen := int(binary.BigEndian.Uint16(pesBuf.buf[4:6])); pesLen != 0 && len(pesBuf.buf) >= 6+pesLen {
+			completed = pesBuf.buf
+			completedOffset = pesBuf.offset
+			pesBuf.buf = nil
+		}
+	}
+	if completed == nil {
+		return nil, nil
+	}
+	frame, err := parsePES(completed)
+	if err != nil {
+		return nil, err
+	}
+	frame.PID = PID
+	d.lastSection = SectionInfo{PID, frame.StreamID, completedOffset}
+	return frame, nil
+}
+
+func parsePES(raw []byte) (*PESFrame, error) {
+	if len(raw) < 6 || raw[0] != 0 || raw[1] != 0 || raw[2] != 1 {
+		return nil, errors.New("illegal PES packet")
+	}
+	frame := PESFrame{StreamID: raw[3]}
+	if pesLen := int(binary.BigEndian.Uint16(raw[4:6])); pesLen != 0 && len(raw) > 6+pesLen {
+		raw = raw[:6+pesLen]
+	}
+	switch frame.StreamID {
+	case 0xBC, PaddingStreamID, PrivateStream2ID, 0xF0, 0xF1, 0xF2, 0xF8, 0xFF:
+		// no PES header
+		frame.Payload = raw[6:]
+		return &frame, nil
+	}
+	if len(raw) < 9 || len(raw) < 9+int(raw[8]) {
+		return nil, errors.New("PES header truncated")
+	}
+	ptsDTSFlags := raw[7] >> 6
+	if ptsDTSFlags&0b10 != 0 && raw[8] >= 5 {
+		frame.HasPTS = true
+		frame.PTS = parseTimestamp(raw[9:14])
+	}
+	if ptsDTSFlags == 0b11 && raw[8] >= 10 {
+		frame.HasDTS = true
+		frame.DTS = parseTimestamp(raw[14:19])
+	}
+	frame.Payload = raw[9+int(raw[8]):]
+	return &frame, nil
+}
+
+func parseTimestamp(raw []byte) uint64 {
+	return uint64(raw[0]>>1&0x07)<<30 | uint64(raw[1])<<22 | uint64(raw[2]>>1)<<15 | uint64(raw[3])<<7 | uint64(raw[4]>>1)
+}
@@ -0,0 +1,46 @@
+package ts
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestParsePMT(t *testing.T) {
+	pmt := []byte{byte(PMTTID), 0xb0, 0, 0x04, 0x00, 0xc1, 0, 0, 0xe1, 0xff, 0xf0, 6,
+		RestrictDescTagID, 4, 0x00, 0x05, 0xe9, 0x01,
+		byte(StreamTypeMPEG2Video), 0xe1, 0x11, 0xf0, 20,
+		StreamIdentifierDescTagID, 1, 0x00,
+		ISO639LangDescTagID, 4, 'j', 'p', 'n', 0,
+		RestrictDescTagID, 4, 0x00, 0x05, 0xe9, 0x02,
+		DataComponentDescTagID, 3, 0x00, 0x08, 0x3d,
+		// truncated descriptors are left as unknown ones
+		byte(StreamTypePESPrivate), 0xe1, 0x30, 0xf0, 9,
+		StreamIdentifierDescTagID, 0,
+		RestrictDescTagID, 2, 0x00, 0x05,
+		DataComponentDescTagID, 1, 0x00,
+		0, 0, 0, 0}
+	frame, err := parsePMT(pmt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pmtFrame := frame.(*PMTFrame)
+	if pmtFrame.ServiceID != 0x400 || pmtFrame.PcrPID != 0x1ff || len(pmtFrame.CA) != 1 || pmtFrame.CA[0].CAPID != 0x901 || len(pmtFrame.StreamList) != 2 {
+		t.Fatalf("unexpected PMT: %+v", pmtFrame)
+	}
+	video := pmtFrame.StreamList[0]
+	if video.PID != 0x111 || !video.HasComponentTag || video.LangCode() != "jpn" || len(video.CA) != 1 || video.CA[0].CAPID != 0x902 ||
+		video.DataComponent.DataComponentId != 0x08 || !bytes.Equal(video.DataComponent.AdditionalIdentification, []byte{0x3d}) || len(video.UnknownDescriptors) != 0 {
+		t.Fatalf("unexpected ES: %+v", video)
+	}
+	truncated := pmtFrame.StreamList[1]
+	if truncated.HasComponentTag || len(truncated.CA) != 0 || truncated.DataComponent.DataComponentId != 0 || len(truncated.UnknownDescriptors) != 3 {
+		t.Fatalf("unexpected ES of truncated descriptors: %+v", truncated)
+	}
+
+	if _, err := parsePMT([]byte{byte(PMTTID), 0xb0, 0, 0x04, 0x00, 0xc1, 0, 0, 0xe1, 0xff, 0xf0, 0, byte(StreamTypeMPEG2Video), 0xe1, 0x11, 0xf0, 20, 0, 0, 0, 0}, nil); err == nil {
+		t.Fatal("ES info length over the section should be an error")
+	}
+	if _, err := parsePMT([]byte{byte(PMTTID), 0xb0, 0, 0x04, 0x00, 0xc1, 0, 0, 0xe1, 0xff, 0xf0, 9, 0, 0, 0, 0}, nil); err == nil {
+		t.Fatal("program info length over the section should be an error")
+	}
+}
@@ -0,0 +1,324 @@
+package ts
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"sort"
+	"time"
+)
+
+const (
+	// DefaultProbeHeadSize is how much of the beginning of TS Probe reads for PSI/SI
+	DefaultProbeHeadSize int64 = 32 << 20
+	// DefaultProbeTailSize is how much of the end of TS Probe reads for the last PCR and TOT
+	DefaultProbeTailSize int64 = 8 << 20
+)
+
+// ProbeResult is the summary of a TS file given by Probe
+type ProbeResult struct {
+	Size              int64  `json:"size"`
+	TransportStreamID uint16 `json:"transport_stream_id"`
+	OriginalNetworkID uint16 `json:"original_network_id"`
+	// Duration is in seconds by PCR of the first service
+	Duration float64 `json:"duration"`
+	// StartTime and EndTime are the first and the last TOT
+	StartTime *time.Time     `json:"start_time,omitempty"`
+	EndTime   *time.Time     `json:"end_time,omitempty"`
+	Services  []ProbeService `json:"services"`
+	Errors    ProbeErrors    `json:"errors"`
+}
+
+// ProbeService is a program listed in PAT, with the name by SDT and the streams by PMT
+type ProbeService struct {
+	ServiceID uint16 `json:"service_id"`
+	Name      string `json:"name,omitempty"`
+	Provider  string `json:"provider,omitempty"`
+	Type      string `json:"type,omitempty"`
+	PMTPID    uint16 `json:"pmt_pid"`
+	PCRPID    uint16 `json:"pcr_pid"`
+	Scrambled bool   `json:"scrambled"`
+	// Duration is in seconds by PCR on PCRPID
+	Duration    float64       `json:"duration"`
+	Streams     []ProbeStream `json:"streams"`
+	Present     *ProbeEvent   `json:"present,omitempty"`
+	Following   *ProbeEvent   `json:"following,omitempty"`
+	Captions    *ProbeCaption `json:"captions,omitempty"`
+	Superimpose *ProbeCaption `json:"superimpose,omitempty"`
+}
+
+// ProbeStream is an ES listed in PMT
+type ProbeStream struct {
+	PID          uint16     `json:"pid"`
+	StreamType   StreamType `json:"stream_type"`
+	Type         string     `json:"type"`
+	ComponentTag *uint8     `json:"component_tag,omitempty"`
+	Languages    []string   `json:"languages,omitempty"`
+	Packets      uint64     `json:"packets"`
+}
+
+// ProbeEvent is the present or following event by EIT p/f
+type ProbeEvent struct {
+	EventID uint16    `json:"event_id"`
+	Name    string    `json:"name"`
+	Text    string    `json:"text,omitempty"`
+	Start   time.Time `json:"start"`
+	// Duration is in seconds, or 0 if undefined
+	Duration float64  `json:"duration"`
+	Genres   []string `json:"genres,omitempty"`
+}
+
+// ProbeCaption is the caption or superimpose ES of a service, with what is found in its PES
+type ProbeCaption struct {
+	PID       uint16   `json:"pid"`
+	Languages []string `json:"languages,omitempty"`
+	// Statements counts caption statement data, telling whether captions are actually present
+	Statements int `json:"statements"`
+}
+
+// ProbeErrors is the error statistics of the packets read
+type ProbeErrors struct {
+	Packets          uint64 `json:"packets"`
+	SyncErrors       uint64 `json:"sync_errors"`
+	TransportErrors  uint64 `json:"transport_errors"`
+	ContinuityErrors uint64 `json:"continuity_errors"`
+	Scrambled        uint64 `json:"scrambled_packets"`
+	// SectionErrors counts PSI/SI sections and PES failed to be parsed
+	SectionErrors uint64 `json:"section_errors"`
+}
+
+type prober struct {
+	result   ProbeResult
+	services map[uint16]*ProbeService
+	// captions maps the PID of caption or superimpose ES to its summary
+	captions map[uint16]*ProbeCaption
+	// names is by SDT, which may list services not in PAT, e.g. of a partial TS
+	names   map[uint16]ServiceDescriptor
+	decoder *Decoder
+}
+
+// Probe reads the beginning of headSize bytes and the end of tailSize bytes of TS, and summarizes it.
+// Broken sections are counted in the errors of the result, and the ReadError is returned when the input fails.
+func Probe(r io.ReadSeeker, headSize int64, tailSize int64) (*ProbeResult, error) {
+	size, err := r.Seek(0, io.SeekEnd)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	p := &prober{services: map[uint16]*ProbeService{}, captions: map[uint16]*ProbeCaption{}, names: map[uint16]ServiceDescriptor{}}
+	p.result.Size = size
+	p.decoder = NewDecoder(io.LimitReader(r, headSize))
+	stats := p.decoder.CollectStats()
+	if err := p.run(); err != nil {
+		return nil, err
+	}
+	var tail *PacketStats
+	if size > headSize {
+		offset := size - tailSize
+		if offset < headSize {
+			offset = headSize
+		}
+		offset -= offset % int64(PacketLength)
+		if _, err := r.Seek(offset, io.SeekStart); err != nil {
+			return nil, err
+		}
+		p.decoder = NewDecoder(r)
+		tail = p.decoder.CollectStats()
+		for PID := range p.captions {
+			p.decoder.WatchPES(PID, false)
+		}
+		if err := p.run(); err != nil {
+			return nil, err
+		}
+	}
+	p.summarize(stats, tail)
+	return &p.result, nil
+}
+
+// run handles the frames to the end of the input, counting broken sections, and returns the ReadError if the input fails
+func (p *prober) run() error {
+	for {
+		frame, err := p.decoder.ParseNext()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil
+		}
+		var readErr *ReadError
+		if errors.As(err, &readErr) {
+			return err
+		}
+		if err != nil {
+			p.result.Errors.SectionErrors++
+			continue
+		}
+		p.handleFrame(frame)
+	}
+}
+
+func (p *prober) service(serviceID uint16) *ProbeService {
+	service, ok := p.services[serviceID]
+	if !ok {
+		service = &ProbeService{ServiceID: serviceID}
+		p.services[serviceID] = service
+	}
+	return service
+}
+
+func (p *prober) handleFrame(frame Frame) {
+	switch f := frame.(type) {
+	case *PATFrame:
+		if len(f.TransportStreamID) == 2 {
+			p.result.TransportStreamID = binary.BigEndian.Uint16(f.TransportStreamID)
+		}
+		for serviceID, PID := range f.SidPidMap {
+			p.service(serviceID).PMTPID = PID
+		}
+	case *PMTFrame:
+		p.handlePMT(f)
+	case *SDTFrame:
+		if f.TableID != SDTActualTID {
+			return
+		}
+		p.result.OriginalNetworkID = f.OriginalNetworkID
+		for _, entry := range f.Entries {
+			p.names[entry.ServiceID] = entry.Service
+		}
+	case *EITFrame:
+		if f.TableID != EITCurrentStreamTID || len(f.Entries) == 0 {
+			return
+		}
+		if _, ok := p.services[f.ServiceID]; !ok {
+			return
+		}
+		event := probeEvent(&f.Entries[0])
+		switch f.SectionNumber {
+		case 0:
+			p.services[f.ServiceID].Present = event
+		case 1:
+			p.services[f.ServiceID].Following = event
+		}
+	case *TOTFrame:
+		jst := f.JSTTime
+		if p.result.StartTime == nil {
+			p.result.StartTime = &jst
+		}
+		p.result.EndTime = &jst
+	case *PESFrame:
+		caption, ok := p.captions[f.PID]
+		if !ok {
+			return
+		}
+		group, err := ParseCaptionPES(f.Payload)
+		if err != nil {
+			p.result.Errors.SectionErrors++
+			return
+		}
+		if group.Management == nil {
+			caption.Statements++
+			return
+		}
+		caption.Languages = caption.Languages[:0]
+		for _, language := range group.Management.Languages {
+			caption.Languages = append(caption.Languages, language.LangCode)
+		}
+	}
+}
+
+func (p *prober) handlePMT(pmt *PMTFrame) {
+	if !pmt.CurrentNext {
+		return
+	}
+	service := p.service(pmt.ServiceID)
+	service.PCRPID = pmt.PcrPID
+	service.Scrambled = pmt.IsScrambled()
+	service.Streams = service.Streams[:0]
+	for i := range pmt.StreamList {
+		es := &pmt.StreamList[i]
+		stream := ProbeStream{PID: es.PID, StreamType: es.StreamId, Type: es.Type().String()}
+		if es.HasComponentTag {
+			tag := es.ComponentTag
+			stream.ComponentTag = &tag
+		}
+		for _, language := range es.Languages {
+			stream.Languages = append(stream.Languages, language.LangCode)
+		}
+		service.Streams = append(service.Streams, stream)
+	}
+	if captions := pmt.CaptionStreams(); len(captions) > 0 && service.Captions == nil {
+		service.Captions = p.watchCaption(captions[0].PID)
+	}
+	if superimpose := pmt.SuperimposeStreams(); len(superimpose) > 0 && service.Superimpose == nil {
+		service.Superimpose = p.watchCaption(superimpose[0].PID)
+	}
+}
+
+func (p *prober) watchCaption(PID uint16) *ProbeCaption {
+	caption, ok := p.captions[PID]
+	if !ok {
+		caption = &ProbeCaption{PID: PID}
+		p.captions[PID] = caption
+		p.decoder.WatchPES(PID, false)
+	}
+	return caption
+}
+
+func probeEvent(entry *EITFrameEntry) *ProbeEvent {
+	event := ProbeEvent{
+		EventID:  entry.EventID,
+		Name:     entry.ShortDescriptor.EventName,
+		Text:     entry.ShortDescriptor.Text,
+		Start:    entry.StartTime,
+		Duration: entry.Duration.Seconds(),
+	}
+	for _, genre := range entry.Contents.Entries {
+		event.Genres = append(event.Genres, genre.String())
+	}
+	return &event
+}
+
+// summarize fills the durations by PCR and the error statistics from the stats of the head and the tail
+func (p *prober) summarize(head *PacketStats, tail *PacketStats) {
+	all := &PacketStats{PIDPackets: map[uint16]uint64{}}
+	all.Add(head)
+	if tail != nil {
+		all.Add(tail)
+	}
+	p.result.Errors.Packets = all.Packets
+	p.result.Errors.SyncErrors = all.SyncErrors
+	p.result.Errors.TransportErrors = all.TransportErrors
+	p.result.Errors.ContinuityErrors = all.ContinuityErrors
+	p.result.Errors.Scrambled = all.Scrambled
+
+	var serviceIDs []int
+	for serviceID := range p.services {
+		serviceIDs = append(serviceIDs, int(serviceID))
+	}
+	sort.Ints(serviceIDs)
+	p.result.Services = []ProbeService{}
+	for _, serviceID := range serviceIDs {
+		service := p.services[uint16(serviceID)]
+		if name, ok := p.names[service.ServiceID]; ok {
+			service.Name = name.ServiceName
+			service.Provider = name.ServiceProviderName
+			service.Type = name.ServiceType.String()
+		}
+		if first, last, ok := head.PCRRange(service.PCRPID); ok {
+			if tail != nil {
+				if _, tailLast, ok := tail.PCRRange(service.PCRPID); ok {
+					last = tailLast
+				}
+			}
+			if diff := PTSDiff(last, first); diff > 0 {
+				service.Duration = float64(diff) / float64(PTSClock)
+			}
+		}
+		for i := range service.Streams {
+			service.Streams[i].Packets = all.PIDPackets[service.Streams[i].PID]
+		}
+		if p.result.Duration == 0 {
+			p.result.Duration = service.Duration
+		}
+		p.result.Services = append(p.result.Services, *service)
+	}
+}
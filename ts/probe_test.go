@@ -0,0 +1,129 @@
+package ts
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+// pcrPacket makes a packet of the PID with the PCR base in the adaptation field, and a payload if counter is not negative
+func pcrPacket(PID uint16, pcr uint64, counter int) []byte {
+	packet := []byte{TsSyncCode, byte(PID >> 8), byte(PID), 0x20, 7, 0x10,
+		byte(pcr >> 25), byte(pcr >> 17), byte(pcr >> 9), byte(pcr >> 1), byte(pcr<<7) | 0x7e, 0}
+	if counter >= 0 {
+		packet[3] |= 0x10 | byte(counter)
+	}
+	for len(packet) < int(PacketLength) {
+		packet = append(packet, 0xff)
+	}
+	return packet
+}
+
+func TestPacketStats(t *testing.T) {
+	var ts []byte
+	ts = append(ts, pcrPacket(0x100, 1<<33-90000, 0)...)
+	ts = append(ts, pcrPacket(0x100, 0, 1)...)
+	// dropped counter
+	ts = append(ts, pcrPacket(0x100, 90000, 3)...)
+	// garbage, and a packet after that is read again in alignment
+	ts = append(ts, 0, 0, 0)
+	ts = append(ts, pcrPacket(0x100, 2*90000, 4)...)
+	broken := pcrPacket(0x101, 0, 0)
+	broken[1] |= 0x80
+	ts = append(ts, broken...)
+	ts = append(ts, pcrPacket(0x100, 3*90000, 5)...)
+	ts = append(ts, make([]byte, 100)...)
+
+	d := NewDecoder(bytes.NewReader(ts))
+	stats := d.CollectStats()
+	for {
+		_, err := d.readNextTSPacket()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+	}
+	if stats.Packets != 6 || stats.SyncErrors != 1 || stats.TransportErrors != 1 || stats.ContinuityErrors != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+	first, last, ok := stats.PCRRange(0x100)
+	if !ok || PTSDiff(last, first) != 4*90000 {
+		t.Fatalf("unexpected PCR range: %d %d", first, last)
+	}
+}
+
+func TestProbeSummary(t *testing.T) {
+	p := &prober{services: map[uint16]*ProbeService{}, captions: map[uint16]*ProbeCaption{}, names: map[uint16]ServiceDescriptor{}}
+	p.decoder = NewDecoder(bytes.NewReader(nil))
+	p.handleFrame(&PATFrame{TransportStreamID: []byte{0x7f, 0xe0}, SidPidMap: map[uint16]uint16{1024: 0x1f0}})
+	p.handleFrame(&SDTFrame{TableID: SDTActualTID, OriginalNetworkID: 0x7fe0, Entries: []SDTFrameEntry{
+		{ServiceID: 1024, Service: ServiceDescriptor{ServiceType: 0x01, ServiceName: "テスト1"}},
+		{ServiceID: 1025, Service: ServiceDescriptor{ServiceType: 0x01, ServiceName: "テスト2"}},
+	}})
+	p.handleFrame(&PMTFrame{ServiceID: 1024, CurrentNext: true, PcrPID: 0x1ff, StreamList: []ESInfo{
+		{StreamId: StreamTypeMPEG2Video, PID: 0x111, HasComponentTag: true, ComponentTag: 0x00},
+		{StreamId: StreamTypeAACADTS, PID: 0x112, HasComponentTag: true, ComponentTag: 0x10, Languages: []ISO639LanguageDescriptor{{"jpn", 0}}},
+		{StreamId: StreamTypePESPrivate, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+	}})
+	start := time.Date(2022, 3, 1, 12, 0, 0, 0, time.UTC)
+	p.handleFrame(&EITFrame{TableID: EITCurrentStreamTID, ServiceID: 1024, SectionNumber: 1, Entries: []EITFrameEntry{
+		{EventID: 2, StartTime: start.Add(time.Hour), Duration: 30 * time.Minute, ShortDescriptor: EITShortEventDescriptor{EventName: "次"}},
+	}})
+	p.handleFrame(&EITFrame{TableID: EITCurrentStreamTID, ServiceID: 1024, SectionNumber: 0, Entries: []EITFrameEntry{
+		{EventID: 1, StartTime: start, Duration: time.Hour, ShortDescriptor: EITShortEventDescriptor{EventName: "今"}},
+	}})
+	p.handleFrame(&PESFrame{PID: 0x130, Payload: captionPES(0x00, []byte{0x00, 1, 0x00, 'j', 'p', 'n', 0x80, 0, 0, 0})})
+	p.handleFrame(&PESFrame{PID: 0x130, Payload: captionStatementPES(0, []byte{0xa2})})
+
+	head := p.decoder.CollectStats()
+	for _, packet := range [][]byte{pcrPacket(0x1ff, 0, -1), pcrPacket(0x111, 0, 0), pcrPacket(0x1ff, 10*90000, -1)} {
+		head.observe(packet)
+	}
+	tail := NewDecoder(bytes.NewReader(nil)).CollectStats()
+	tail.observe(pcrPacket(0x1ff, 60*90000, -1))
+	p.summarize(head, tail)
+
+	result := p.result
+	if len(result.Services) != 1 || result.TransportStreamID != 0x7fe0 || result.Duration != 60 || result.Errors.Packets != 4 {
+		t.Fatalf("unexpected result: %+v", result)
+	}
+	service := result.Services[0]
+	if service.Name != "テスト1" || service.PMTPID != 0x1f0 || len(service.Streams) != 3 || service.Streams[0].Packets != 1 ||
+		service.Streams[1].Type != "AAC ADTS" || service.Streams[1].Languages[0] != "jpn" {
+		t.Fatalf("unexpected service: %+v", service)
+	}
+	if service.Present == nil || service.Present.Name != "今" || service.Following == nil || service.Following.Duration != 1800 {
+		t.Fatalf("unexpected events: %+v %+v", service.Present, service.Following)
+	}
+	if service.Captions == nil || service.Captions.Statements != 1 || len(service.Captions.Languages) != 1 || service.Captions.Languages[0] != "jpn" {
+		t.Fatalf("unexpected captions: %+v", service.Captions)
+	}
+	raw, err := json.Marshal(result)
+	if err != nil || !strings.Contains(string(raw), `"name":"テスト1"`) || !strings.Contains(string(raw), `"component_tag":16`) {
+		t.Fatalf("unexpected JSON: %s %v", raw, err)
+	}
+}
+
+// failingReadSeeker is a failingReader claiming the size of a whole file
+type failingReadSeeker struct {
+	failingReader
+}
+
+func (r *failingReadSeeker) Seek(offset int64, whence int) (int64, error) {
+	if whence == io.SeekEnd {
+		return 1 << 30, nil
+	}
+	return offset, nil
+}
+
+func TestProbeReadError(t *testing.T) {
+	packet := bytes.Repeat([]byte{0xff}, int(PacketLength))
+	_, err := Probe(&failingReadSeeker{failingReader{packet}}, 1<<20, 1<<20)
+	var readErr *ReadError
+	if !errors.As(err, &readErr) {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package ts
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// sectionPacket makes a packet starting the section on the PID
+func sectionPacket(PID uint16, counter uint8, section []byte) []byte {
+	packet := append([]byte{TsSyncCode, 0x40 | byte(PID>>8), byte(PID), 0x10 | counter, 0}, section...)
+	for len(packet) < int(PacketLength) {
+		packet = append(packet, 0xff)
+	}
+	return packet
+}
+
+func TestSectionInfoAndUnknownDescriptors(t *testing.T) {
+	pat := []byte{byte(PATTID), 0xb0, 13, 0x7f, 0xe0, 0xc1, 0, 0, 0x04, 0x00, 0xe1, 0xf0, 0, 0, 0, 0}
+	pmt := []byte{byte(PMTTID), 0xb0, 27, 0x04, 0x00, 0xc1, 0, 0, 0xe1, 0xff, 0xf0, 3,
+		0xc1, 1, 0x84, // digital copy control descriptor
+		byte(StreamTypeMPEG2Video), 0xe1, 0x11, 0xf0, 6,
+		StreamIdentifierDescTagID, 1, 0x00,
+		0xc8, 1, 0x01, // video decode control descriptor
+		0, 0, 0, 0}
+	var ts []byte
+	ts = append(ts, sectionPacket(0, 0, pat)...)
+	ts = append(ts, sectionPacket(0, 1, pat)...)
+	ts = append(ts, 'x') // garbage, read again from the next sync code
+	ts = append(ts, sectionPacket(0x1f0, 0, pmt)...)
+	ts = append(ts, sectionPacket(0x1f0, 1, pmt)...)
+	d := NewDecoder(bytes.NewReader(ts))
+
+	frame, err := d.ParseNext()
+	if err != nil || frame.GetType() != "PAT" {
+		t.Fatalf("unexpected frame: %v %v", frame, err)
+	}
+	if info := d.LastSection(); info != (SectionInfo{0, byte(PATTID), 0}) {
+		t.Fatalf("unexpected section info of PAT: %+v", info)
+	}
+	if _, err := d.ParseNext(); err == nil {
+		t.Fatal("garbage should be reported")
+	}
+	frame, err = d.ParseNext()
+	if err != nil || frame.GetType() != "PMT" {
+		t.Fatalf("unexpected frame: %v %v", frame, err)
+	}
+	if info := d.LastSection(); info != (SectionInfo{0x1f0, byte(PMTTID), 2*int64(PacketLength) + 1}) {
+		t.Fatalf("unexpected section info of PMT: %+v", info)
+	}
+	pmtFrame := frame.(*PMTFrame)
+	if len(pmtFrame.UnknownDescriptors) != 1 || pmtFrame.UnknownDescriptors[0].Tag != 0xc1 ||
+		len(pmtFrame.StreamList) != 1 || len(pmtFrame.StreamList[0].UnknownDescriptors) != 1 || !pmtFrame.StreamList[0].HasComponentTag {
+		t.Fatalf("unexpected PMT: %+v", pmtFrame)
+	}
+	raw, err := json.Marshal(pmtFrame.UnknownDescriptors[0])
+	if err != nil || string(raw) != `{"tag":193,"data":"84"}` {
+		t.Fatalf("unexpected JSON: %s %v", raw, err)
+	}
+}
+
+func TestParseSDTTruncatedDescriptors(t *testing.T) {
+	sdt := []byte{byte(SDTActualTID), 0xf0, 0, 0x7f, 0xe0, 0xc1, 0, 0, 0x7f, 0xe0, 0xff,
+		0x04, 0x00, 0xfc, 0x80, 10,
+		ServiceDescTagID, 4, 0x01, 50, 'x', 'y', // provider name longer than the descriptor
+		0xCF, 2, 0x01, 0x00, // logo of type 1 without version
+		0, 0, 0, 0}
+	frame, err := parseSDT(sdt, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entry := frame.(*SDTFrame).Entries[0]
+	if entry.ServiceID != 0x400 || entry.Service.ServiceName != "" || len(entry.UnknownDescriptors) != 2 {
+		t.Fatalf("unexpected SDT entry: %+v", entry)
+	}
+
+	sdt[15] = 40
+	if _, err := parseSDT(sdt, nil); err == nil {
+		t.Fatal("SDT with oversized descriptors length should fail")
+	}
+}
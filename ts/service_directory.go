@@ -0,0 +1,200 @@
+package ts
+
+import "sort"
+
+type ServiceKey struct {
+	OriginalNetworkID uint16
+	TransportStreamID uint16
+	ServiceID         uint16
+}
+
+type ServiceInfo struct {
+	ServiceKey
+	ServiceName        string
+	ProviderName       string
+	ServiceType        ServiceType
+	RemoteControlKeyID uint8
+	TSName             string
+	Logo               LogoTransmissionDescriptor
+}
+
+// HasLogo reports whether the service announces a logo to be found in CDT
+func (s *ServiceInfo) HasLogo() bool {
+	return s.Logo.LogoTransmissionType == 0x01 || s.Logo.LogoTransmissionType == 0x02
+}
+
+type tsKey struct {
+	onid uint16
+	tsid uint16
+}
+
+type directoryTransport struct {
+	networkID uint16
+	entry     NITTransportEntry
+}
+
+// ServiceDirectory joins NIT and SDT, both actual and other, into a network-wide service list.
+// Services announced by an older version of the table are dropped when a new version arrives.
+type ServiceDirectory struct {
+	nitVersions map[uint16]uint8
+	sdtVersions map[tsKey]uint8
+	transports  map[tsKey]*directoryTransport
+	sdtEntries  map[ServiceKey]SDTFrameEntry
+}
+
+func NewServiceDirectory() *ServiceDirectory {
+	return &ServiceDirectory{
+		make(map[uint16]uint8),
+		make(map[tsKey]uint8),
+		make(map[tsKey]*directoryTransport),
+		make(map[ServiceKey]SDTFrameEntry),
+	}
+}
+
+// Update applies NIT and SDT frames and ignores others. It returns true if the directory is changed.
+func (d *ServiceDirectory) Update(frame Frame) bool {
+	switch f := frame.(type) {
+	case *NITFrame:
+		return d.updateNIT(f)
+	case *SDTFrame:
+		return d.updateSDT(f)
+	}
+	return false
+}
+
+func (d *ServiceDirectory) updateNIT(frame *NITFrame) bool {
+	if !frame.CurrentNext {
+		return false
+	}
+	changed := false
+	if version, ok := d.nitVersions[frame.NetworkID]; !ok || version != frame.Version {
+		for key, transport := range d.transports {
+			if transport.networkID == frame.NetworkID {
+				delete(d.transports, key)
+			}
+		}
+		d.nitVersions[frame.NetworkID] = frame.Version
+		changed = true
+	}
+	for _, entry := range frame.TransportStreams {
+		key := tsKey{entry.OriginalNetworkId, entry.TransportStreamId}
+		if _, ok := d.transports[key]; !ok {
+			changed = true
+		}
+		d.transports[key] = &directoryTransport{frame.NetworkID, entry}
+	}
+	return changed
+}
+
+func (d *ServiceDirectory) updateSDT(frame *SDTFrame) bool {
+	if !frame.CurrentNext {
+		return false
+	}
+	changed := false
+	tk := tsKey{frame.OriginalNetworkID, frame.TransportStreamID}
+	if version, ok := d.sdtVersions[tk]; !ok || version != frame.Version {
+		for key := range d.sdtEntries {
+			if key.OriginalNetworkID == tk.onid && key.TransportStreamID == tk.tsid {
+				delete(d.sdtEntries, key)
+			}
+		}
+		d.sdtVersions[tk] = frame.Version
+		changed = true
+	}
+	for _, entry := range frame.Entries {
+		key := ServiceKey{frame.OriginalNetworkID, frame.TransportStreamID, entry.ServiceID}
+		if _, ok := d.sdtEntries[key]; !ok {
+			changed = true
+		}
+		d.sdtEntries[key] = entry
+	}
+	return changed
+}
+
+func (d *ServiceDirectory) keys() map[ServiceKey]bool {
+	keys := make(map[ServiceKey]bool)
+	for tk, transport := range d.transports {
+		for sid := range transport.entry.ServiceList {
+			keys[ServiceKey{tk.onid, tk.tsid, sid}] = true
+		}
+	}
+	for key := range d.sdtEntries {
+		keys[key] = true
+	}
+	return keys
+}
+
+func (d *ServiceDirectory) info(key ServiceKey) *ServiceInfo {
+	info := ServiceInfo{ServiceKey: key}
+	found := false
+	if transport, ok := d.transports[tsKey{key.OriginalNetworkID, key.TransportStreamID}]; ok {
+		info.RemoteControlKeyID = transport.entry.TSInfo.RemoteControlKeyId
+		info.TSName = transport.entry.TSInfo.TSName
+		if serviceType, ok := transport.entry.ServiceList[key.ServiceID]; ok {
+			info.ServiceType = serviceType
+			found = true
+		}
+	}
+	if entry, ok := d.sdtEntries[key]; ok {
+		info.ServiceName = entry.Service.ServiceName
+		info.ProviderName = entry.Service.ServiceProviderName
+		info.ServiceType = entry.Service.ServiceType
+		info.Logo = entry.Logo
+		found = true
+	}
+	if !found {
+		return nil
+	}
+	return &info
+}
+
+// Lookup returns the service, or nil if not found
+func (d *ServiceDirectory) Lookup(onid uint16, tsid uint16, sid uint16) *ServiceInfo {
+	return d.info(ServiceKey{onid, tsid, sid})
+}
+
+// FindService returns the service without knowing which TS it lives on, or nil if not found.
+// If the SID is found on several TSs, the one of the lowest TSID is returned.
+func (d *ServiceDirectory) FindService(onid uint16, sid uint16) *ServiceInfo {
+	var found *ServiceKey
+	for key := range d.keys() {
+		if key.OriginalNetworkID == onid && key.ServiceID == sid && (found == nil || key.TransportStreamID < found.TransportStreamID) {
+			key := key
+			found = &key
+		}
+	}
+	if found == nil {
+		return nil
+	}
+	return d.info(*found)
+}
+
+// Services returns all services sorted by ONID, TSID and SID
+func (d *ServiceDirectory) Services() []ServiceInfo {
+	result := make([]ServiceInfo, 0)
+	for key := range d.keys() {
+		result = append(result, *d.info(key))
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i].ServiceKey, result[j].ServiceKey
+		if a.OriginalNetworkID != b.OriginalNetworkID {
+			return a.OriginalNetworkID < b.OriginalNetworkID
+		}
+		if a.TransportStreamID != b.TransportStreamID {
+			return a.TransportStreamID < b.TransportStreamID
+		}
+		return a.ServiceID < b.ServiceID
+	})
+	return result
+}
+
+// ServicesOn returns services living on the given TS
+func (d *ServiceDirectory) ServicesOn(onid uint16, tsid uint16) []ServiceInfo {
+	result := make([]ServiceInfo, 0)
+	for _, service := range d.Services() {
+		if service.OriginalNetworkID == onid && service.TransportStreamID == tsid {
+			result = append(result, service)
+		}
+	}
+	return result
+}
@@ -0,0 +1,52 @@
+package ts
+
+import "testing"
+
+func TestServiceDirectoryUpdate(t *testing.T) {
+	directory := NewServiceDirectory()
+	directory.Update(&NITFrame{TableID: NITActualTID, NetworkID: 4, CurrentNext: true, Version: 1,
+		TransportStreams: []NITTransportEntry{
+			{TransportStreamId: 0x4010, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01, 102: 0x01}, TSInfo: TSInfo{1, "BS1"}},
+			{TransportStreamId: 0x4011, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{103: 0x01}, TSInfo: TSInfo{3, "BS3"}},
+		}})
+	directory.Update(&SDTFrame{TableID: SDTOtherTID, TransportStreamID: 0x4011, OriginalNetworkID: 4, CurrentNext: true, Version: 1,
+		Entries: []SDTFrameEntry{{ServiceID: 103, Service: ServiceDescriptor{0x01, "NHK", "NHK BSプレミアム"}, Logo: LogoTransmissionDescriptor{LogoTransmissionType: 0x02, LogoId: 5}}}})
+
+	if len(directory.Services()) != 3 {
+		t.Fatalf("unexpected services: %v", directory.Services())
+	}
+	service := directory.FindService(4, 103)
+	if service == nil || service.TransportStreamID != 0x4011 || service.ServiceName != "NHK BSプレミアム" || service.RemoteControlKeyID != 3 || !service.HasLogo() || service.Logo.LogoId != 5 {
+		t.Fatalf("unexpected service: %+v", service)
+	}
+
+	if !directory.Update(&SDTFrame{TableID: SDTOtherTID, TransportStreamID: 0x4011, OriginalNetworkID: 4, CurrentNext: true, Version: 2,
+		Entries: []SDTFrameEntry{{ServiceID: 103, Service: ServiceDescriptor{0x01, "NHK", "NHK BS4K"}}}}) {
+		t.Fatal("new version should change the directory")
+	}
+	if service := directory.Lookup(4, 0x4011, 103); service == nil || service.ServiceName != "NHK BS4K" || service.HasLogo() {
+		t.Fatalf("service is not updated: %+v", service)
+	}
+	directory.Update(&NITFrame{TableID: NITActualTID, NetworkID: 4, CurrentNext: true, Version: 2,
+		TransportStreams: []NITTransportEntry{
+			{TransportStreamId: 0x4010, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01}, TSInfo: TSInfo{1, "BS1"}},
+		}})
+	if directory.Lookup(4, 0x4010, 102) != nil {
+		t.Fatal("service dropped by new NIT version should be removed")
+	}
+}
+
+func TestServiceDirectoryFindServiceOnSeveralTS(t *testing.T) {
+	directory := NewServiceDirectory()
+	directory.Update(&NITFrame{TableID: NITActualTID, NetworkID: 4, CurrentNext: true, Version: 1,
+		TransportStreams: []NITTransportEntry{
+			{TransportStreamId: 0x4012, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01}, TSInfo: TSInfo{1, "BS1 B"}},
+			{TransportStreamId: 0x4010, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01}, TSInfo: TSInfo{1, "BS1"}},
+			{TransportStreamId: 0x4011, OriginalNetworkId: 4, ServiceList: map[uint16]ServiceType{101: 0x01}, TSInfo: TSInfo{1, "BS1 A"}},
+		}})
+	for i := 0; i < 20; i++ {
+		if service := directory.FindService(4, 101); service == nil || service.TransportStreamID != 0x4010 {
+			t.Fatalf("unexpected service: %+v", service)
+		}
+	}
+}
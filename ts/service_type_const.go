@@ -0,0 +1,82 @@
+package ts
+
+import "strconv"
+
+const (
+	ServiceTypeDigitalTV    ServiceType = 0x01
+	ServiceTypeDigitalAudio ServiceType = 0x02
+)
+
+const (
+	ServiceTypeTemporaryVideo ServiceType = iota + 0xA1
+	ServiceTypeTemporaryAudio
+	ServiceTypeTemporaryData
+	ServiceTypeEngineering
+	ServiceTypePromotionVideo
+	ServiceTypePromotionAudio
+	ServiceTypePromotionData
+	ServiceTypePreAccumulationData
+	ServiceTypeAccumulationData
+	ServiceTypeBookmarkListData
+	ServiceTypeServerSimulcast
+	ServiceTypeIndependentFile
+	ServiceTypeUHDTV
+)
+
+const (
+	ServiceTypeData ServiceType = iota + 0xC0
+	ServiceTypeTLVAccumulation
+	ServiceTypeMultimedia
+)
+
+var serviceTypeNames = map[ServiceType]genreName{
+	ServiceTypeDigitalTV:           {"デジタルTVサービス", "Digital TV"},
+	ServiceTypeDigitalAudio:        {"デジタル音声サービス", "Digital Audio"},
+	ServiceTypeTemporaryVideo:      {"臨時映像サービス", "Temporary Video"},
+	ServiceTypeTemporaryAudio:      {"臨時音声サービス", "Temporary Audio"},
+	ServiceTypeTemporaryData:       {"臨時データサービス", "Temporary Data"},
+	ServiceTypeEngineering:         {"エンジニアリングサービス", "Engineering"},
+	ServiceTypePromotionVideo:      {"プロモーション映像サービス", "Promotion Video"},
+	ServiceTypePromotionAudio:      {"プロモーション音声サービス", "Promotion Audio"},
+	ServiceTypePromotionData:       {"プロモーションデータサービス", "Promotion Data"},
+	ServiceTypePreAccumulationData: {"事前蓄積用データサービス", "Pre-accumulation Data"},
+	ServiceTypeAccumulationData:    {"蓄積専用データサービス", "Accumulation-only Data"},
+	ServiceTypeBookmarkListData:    {"ブックマーク一覧データサービス", "Bookmark List Data"},
+	ServiceTypeServerSimulcast:     {"サーバー型サイマルサービス", "Server-type Simulcast"},
+	ServiceTypeIndependentFile:     {"独立ファイルサービス", "Independent File"},
+	ServiceTypeUHDTV:               {"超高精細度4K専用TVサービス", "4K UHD TV"},
+	ServiceTypeData:                {"データサービス", "Data"},
+	ServiceTypeTLVAccumulation:     {"TLVを用いた蓄積型サービス", "TLV Accumulation"},
+	ServiceTypeMultimedia:          {"マルチメディアサービス", "Multimedia"},
+}
+
+func (v ServiceType) String() string {
+	if name, ok := serviceTypeNames[v]; ok {
+		return name.en
+	}
+	return "0x" + strconv.FormatUint(uint64(v), 16)
+}
+
+func (v ServiceType) JapaneseName() string {
+	if name, ok := serviceTypeNames[v]; ok {
+		return name.ja
+	}
+	return "0x" + strconv.FormatUint(uint64(v), 16)
+}
+
+func (v ServiceType) IsTV() bool {
+	return v == ServiceTypeDigitalTV || v == ServiceTypeTemporaryVideo || v == ServiceTypePromotionVideo || v == ServiceTypeUHDTV
+}
+
+func (v ServiceType) IsRadio() bool {
+	return v == ServiceTypeDigitalAudio || v == ServiceTypeTemporaryAudio || v == ServiceTypePromotionAudio
+}
+
+func (v ServiceType) IsData() bool {
+	switch v {
+	case ServiceTypeTemporaryData, ServiceTypePromotionData, ServiceTypePreAccumulationData, ServiceTypeAccumulationData,
+		ServiceTypeBookmarkListData, ServiceTypeData, ServiceTypeTLVAccumulation, ServiceTypeMultimedia:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package ts
+
+// NullPID is the PID of null packets, which are not counted for continuity
+const NullPID uint16 = 0x1FFF
+
+// PacketStats counts the TS packets read by a decoder and the errors in them
+type PacketStats struct {
+	Packets         uint64
+	SyncErrors      uint64
+	TransportErrors uint64
+	// ContinuityErrors counts drops of continuity counter, except those with discontinuity indicator
+	ContinuityErrors uint64
+	Scrambled        uint64
+	PIDPackets       map[uint16]uint64
+
+	lastCounter map[uint16]uint8
+	pcr         map[uint16]*pcrRange
+}
+
+type pcrRange struct {
+	first uint64
+	last  uint64
+}
+
+// CollectStats makes the decoder count the packets from now on, and gives the stats to be updated
+func (d *Decoder) CollectStats() *PacketStats {
+	d.stats = &PacketStats{PIDPackets: map[uint16]uint64{}, lastCounter: map[uint16]uint8{}, pcr: map[uint16]*pcrRange{}}
+	return d.stats
+}
+
+// PCRRange gives the first and the last PCR base in 90kHz seen on the PID
+func (s *PacketStats) PCRRange(PID uint16) (first uint64, last uint64, ok bool) {
+	r, ok := s.pcr[PID]
+	if !ok {
+		return 0, 0, false
+	}
+	return r.first, r.last, true
+}
+
+// Add sums the counts of other, e.g. of another part of the same file
+func (s *PacketStats) Add(other *PacketStats) {
+	s.Packets += other.Packets
+	s.SyncErrors += other.SyncErrors
+	s.TransportErrors += other.TransportErrors
+	s.ContinuityErrors += other.ContinuityErrors
+	s.Scrambled += other.Scrambled
+	for PID, count := range other.PIDPackets {
+		s.PIDPackets[PID] += count
+	}
+}
+
+func (s *PacketStats) observe(packet []byte) {
+	s.Packets++
+	PID := uint16(packet[1])<<8&PIDMask | uint16(packet[2])
+	s.PIDPackets[PID]++
+	if packet[1]&0x80 != 0 {
+		s.TransportErrors++
+		// the header itself may be broken
+		return
+	}
+	if packet[3]>>6 != 0 {
+		s.Scrambled++
+	}
+	discontinuity := false
+	if AdaptationFieldMask&packet[3] == AdaptationFieldMask && packet[4] > 0 {
+		flags := packet[5]
+		discontinuity = flags&0x80 != 0
+		if flags&0x10 != 0 && packet[4] >= 7 {
+			pcr := uint64(packet[6])<<25 | uint64(packet[7])<<17 | uint64(packet[8])<<9 | uint64(packet[9])<<1 | uint64(packet[10])>>7
+			if r, ok := s.pcr[PID]; ok {
+				r.last = pcr
+			} else {
+				s.pcr[PID] = &pcrRange{pcr, pcr}
+			}
+		}
+	}
+	if PID == NullPID || PayloadFlagMask&packet[3] != PayloadFlagMask {
+		return
+	}
+	counter := packet[3] & CounterMask
+	if last, ok := s.lastCounter[PID]; ok && !discontinuity && counter != last && counter != (last+1)&CounterMask {
+		s.ContinuityErrors++
+	}
+	s.lastCounter[PID] = counter
+}
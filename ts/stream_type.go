@@ -0,0 +1,149 @@
+package ts
+
+type ESType uint8
+
+const (
+	ESTypeUnknown ESType = iota
+	ESTypeMPEG2Video
+	ESTypeH264
+	ESTypeH265
+	ESTypeAACADTS
+	ESTypeAACLATM
+	ESTypeARIBCaption
+	ESTypeARIBSuperimpose
+	ESTypeDSMCCCarousel
+	ESTypeMPEG1Video
+)
+
+// component tag ranges by ARIB TR-B14/TR-B15
+const (
+	VideoComponentTagMin           uint8 = 0x00
+	VideoComponentTagMax           uint8 = 0x0F
+	AudioComponentTagMin           uint8 = 0x10
+	AudioComponentTagMax           uint8 = 0x2F
+	CaptionComponentTagMin         uint8 = 0x30
+	CaptionComponentTagMax         uint8 = 0x37
+	SuperimposeComponentTagMin     uint8 = 0x38
+	SuperimposeComponentTagMax     uint8 = 0x3F
+	DataComponentTagMin            uint8 = 0x40
+	DataComponentTagMax            uint8 = 0x7F
+	PartialCaptionComponentTag     uint8 = 0x87
+	PartialSuperimposeComponentTag uint8 = 0x88
+
+	DefaultVideoComponentTag uint8 = 0x00
+	DefaultAudioComponentTag uint8 = 0x10
+)
+
+func (t ESType) String() string {
+	switch t {
+	case ESTypeMPEG1Video:
+		return "MPEG-1 Video"
+	case ESTypeMPEG2Video:
+		return "MPEG-2 Video"
+	case ESTypeH264:
+		return "H.264"
+	case ESTypeH265:
+		return "H.265"
+	case ESTypeAACADTS:
+		return "AAC ADTS"
+	case ESTypeAACLATM:
+		return "AAC LATM"
+	case ESTypeARIBCaption:
+		return "ARIB Caption"
+	case ESTypeARIBSuperimpose:
+		return "ARIB Superimpose"
+	case ESTypeDSMCCCarousel:
+		return "DSM-CC Data Carousel"
+	default:
+		return "Unknown"
+	}
+}
+
+func (t ESType) IsVideo() bool {
+	return t == ESTypeMPEG1Video || t == ESTypeMPEG2Video || t == ESTypeH264 || t == ESTypeH265
+}
+
+func (t ESType) IsAudio() bool {
+	return t == ESTypeAACADTS || t == ESTypeAACLATM
+}
+
+// Type classifies the ES by stream_type, and by component tag for PES private data
+func (e *ESInfo) Type() ESType {
+	switch e.StreamId {
+	case StreamTypeMPEG1Video:
+		return ESTypeMPEG1Video
+	case StreamTypeMPEG2Video:
+		return ESTypeMPEG2Video
+	case StreamTypeH264:
+		return ESTypeH264
+	case StreamTypeH265:
+		return ESTypeH265
+	case StreamTypeAACADTS:
+		return ESTypeAACADTS
+	case StreamTypeAACLATM:
+		return ESTypeAACLATM
+	case StreamTypeDSMCCTypeB, StreamTypeDSMCCTypeD:
+		return ESTypeDSMCCCarousel
+	case StreamTypePESPrivate:
+		if !e.HasComponentTag {
+			return ESTypeUnknown
+		}
+		tag := e.ComponentTag
+		if (CaptionComponentTagMin <= tag && tag <= CaptionComponentTagMax) || tag == PartialCaptionComponentTag {
+			return ESTypeARIBCaption
+		}
+		if (SuperimposeComponentTagMin <= tag && tag <= SuperimposeComponentTagMax) || tag == PartialSuperimposeComponentTag {
+			return ESTypeARIBSuperimpose
+		}
+	}
+	return ESTypeUnknown
+}
+
+func (f *PMTFrame) streamsOfType(pred func(ESType) bool) []ESInfo {
+	result := make([]ESInfo, 0)
+	for _, es := range f.StreamList {
+		if pred(es.Type()) {
+			result = append(result, es)
+		}
+	}
+	return result
+}
+
+// PrimaryVideo returns the video ES with the default component tag, or the first video ES if none is tagged so.
+// nil is returned if no video ES is found.
+func (f *PMTFrame) PrimaryVideo() *ESInfo {
+	var first *ESInfo
+	for i := range f.StreamList {
+		es := &f.StreamList[i]
+		if !es.Type().IsVideo() {
+			continue
+		}
+		if es.HasComponentTag && es.ComponentTag == DefaultVideoComponentTag {
+			return es
+		}
+		if first == nil {
+			first = es
+		}
+	}
+	return first
+}
+
+func (f *PMTFrame) VideoStreams() []ESInfo {
+	return f.streamsOfType(ESType.IsVideo)
+}
+
+func (f *PMTFrame) AudioTracks() []ESInfo {
+	return f.streamsOfType(ESType.IsAudio)
+}
+
+func (f *PMTFrame) CaptionStreams() []ESInfo {
+	return f.streamsOfType(func(t ESType) bool { return t == ESTypeARIBCaption })
+}
+
+func (f *PMTFrame) SuperimposeStreams() []ESInfo {
+	return f.streamsOfType(func(t ESType) bool { return t == ESTypeARIBSuperimpose })
+}
+
+func (f *PMTFrame) DataCarouselStreams() []ESInfo {
+	return f.streamsOfType(func(t ESType) bool { return t == ESTypeDSMCCCarousel })
+}
@@ -0,0 +1,38 @@
+package ts
+
+import "testing"
+
+func TestPMTStreamClassification(t *testing.T) {
+	pmt := PMTFrame{StreamList: []ESInfo{
+		{StreamId: 0x02, PID: 0x111, HasComponentTag: true, ComponentTag: 0x01},
+		{StreamId: 0x02, PID: 0x100, HasComponentTag: true, ComponentTag: 0x00},
+		{StreamId: 0x0F, PID: 0x110, HasComponentTag: true, ComponentTag: 0x10},
+		{StreamId: 0x0F, PID: 0x112, HasComponentTag: true, ComponentTag: 0x11},
+		{StreamId: 0x06, PID: 0x130, HasComponentTag: true, ComponentTag: 0x30},
+		{StreamId: 0x06, PID: 0x138, HasComponentTag: true, ComponentTag: 0x38},
+		{StreamId: 0x0D, PID: 0x140, HasComponentTag: true, ComponentTag: 0x40},
+		{StreamId: 0x06, PID: 0x150},
+	}}
+	if v := pmt.PrimaryVideo(); v == nil || v.PID != 0x100 {
+		t.Fatalf("unexpected primary video: %v", v)
+	}
+	if audio := pmt.AudioTracks(); len(audio) != 2 || audio[0].PID != 0x110 {
+		t.Fatalf("unexpected audio tracks: %v", audio)
+	}
+	if captions := pmt.CaptionStreams(); len(captions) != 1 || captions[0].PID != 0x130 {
+		t.Fatalf("unexpected caption streams: %v", captions)
+	}
+	if superimposes := pmt.SuperimposeStreams(); len(superimposes) != 1 || superimposes[0].PID != 0x138 {
+		t.Fatalf("unexpected superimpose streams: %v", superimposes)
+	}
+	if data := pmt.DataCarouselStreams(); len(data) != 1 || data[0].PID != 0x140 {
+		t.Fatalf("unexpected data carousel streams: %v", data)
+	}
+	if pmt.StreamList[7].Type() != ESTypeUnknown {
+		t.Fatalf("untagged PES private should be unknown")
+	}
+	mpeg1 := ESInfo{StreamId: StreamTypeMPEG1Video, PID: 0x101}
+	if mpeg1.Type() != ESTypeMPEG1Video || mpeg1.Type().String() != "MPEG-1 Video" || !mpeg1.Type().IsVideo() {
+		t.Fatalf("unexpected MPEG-1 video type: %v", mpeg1.Type())
+	}
+}
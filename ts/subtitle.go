@@ -0,0 +1,189 @@
+package ts
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
+const (
+	// assPlayResX and assPlayResY are the caption display area of 960x540
+	assPlayResX = 960
+	assPlayResY = 540
+)
+
+func formatSubtitleTime(d time.Duration, fractionSep string) string {
+	ms := d.Milliseconds()
+	return fmt.Sprintf("%02d:%02d:%02d%s%03d", ms/3600000, ms/60000%60, ms/1000%60, fractionSep, ms%1000)
+}
+
+// cueText gives the text of the cue without empty lines, as an empty line would end the cue in SRT and WebVTT
+func cueText(cue *CaptionCue) string {
+	lines := strings.Split(cue.Text(), "\n")
+	kept := lines[:0]
+	for _, line := range lines {
+		if line != "" {
+			kept = append(kept, line)
+		}
+	}
+	return strings.Join(kept, "\n")
+}
+
+// WriteSRT writes the cues in SubRip format
+func WriteSRT(w io.Writer, cues []CaptionCue) error {
+	bw := bufio.NewWriter(w)
+	for i, cue := range cues {
+		fmt.Fprintf(bw, "%d\n%s --> %s\n%s\n\n", i+1, formatSubtitleTime(cue.Start, ","), formatSubtitleTime(cue.End, ","), cueText(&cue))
+	}
+	return bw.Flush()
+}
+
+// WriteWebVTT writes the cues in WebVTT format
+func WriteWebVTT(w io.Writer, cues []CaptionCue) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("WEBVTT\n\n")
+	for _, cue := range cues {
+		fmt.Fprintf(bw, "%s --> %s\n%s\n\n", formatSubtitleTime(cue.Start, "."), formatSubtitleTime(cue.End, "."), cueText(&cue))
+	}
+	return bw.Flush()
+}
+
+func formatASSTime(d time.Duration) string {
+	cs := d.Milliseconds() / 10
+	return fmt.Sprintf("%d:%02d:%02d.%02d", cs/360000, cs/6000%60, cs/100%60, cs%100)
+}
+
+// assColor gives the color of the index of b24.DefaultCLUT in ASS override format
+func assColor(index uint8) string {
+	r, g, b, _ := b24.DefaultCLUT[index].RGBA()
+	return fmt.Sprintf("&H%02X%02X%02X&", b>>8, g>>8, r>>8)
+}
+
+// assText gives the text of the cue with override tags for the position and the colors
+func assText(cue *CaptionCue) string {
+	var sb strings.Builder
+	positioned := false
+	color := b24.DefaultStyle.Foreground
+	for _, run := range cue.Runs {
+		text := strings.ReplaceAll(run.Text, "\f", "")
+		if run.HasPosition && !positioned && strings.TrimLeft(text, "\n") != "" {
+			// the active position is at the bottom left of the character, scaled from the plane of the format to 960x540
+			x, y := cue.Format.Dot(run.Position, run.Size)
+			w, h := cue.Format.Plane()
+			x, y = x*assPlayResX/w, y*assPlayResY/h
+			fmt.Fprintf(&sb, "{\\an1\\pos(%d,%d)}", x, y)
+			positioned = true
+		}
+		if text == "" {
+			continue
+		}
+		if run.Foreground != color {
+			fmt.Fprintf(&sb, "{\\c%s}", assColor(run.Foreground))
+			color = run.Foreground
+		}
+		sb.WriteString(text)
+	}
+	text := strings.Trim(sb.String(), "\n")
+	return strings.ReplaceAll(text, "\n", "\\N")
+}
+
+// WriteASS writes the cues in Advanced SubStation Alpha format, keeping the positions and the colors
+func WriteASS(w io.Writer, cues []CaptionCue) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "[Script Info]\nScriptType: v4.00+\nPlayResX: %d\nPlayResY: %d\nWrapStyle: 2\n\n", assPlayResX, assPlayResY)
+	bw.WriteString("[V4+ Styles]\n")
+	bw.WriteString("Format: Name, Fontname, Fontsize, PrimaryColour, SecondaryColour, OutlineColour, BackColour, Bold, Italic, Underline, StrikeOut, ScaleX, ScaleY, Spacing, Angle, BorderStyle, Outline, Shadow, Alignment, MarginL, MarginR, MarginV, Encoding\n")
+	bw.WriteString("Style: Default,sans-serif,36,&H00FFFFFF,&H000000FF,&H00000000,&H80000000,0,0,0,0,100,100,4,0,1,2,0,2,20,20,20,128\n\n")
+	bw.WriteString("[Events]\n")
+	bw.WriteString("Format: Layer, Start, End, Style, Name, MarginL, MarginR, MarginV, Effect, Text\n")
+	for i := range cues {
+		fmt.Fprintf(bw, "Dialogue: 0,%s,%s,Default,,0,0,0,,%s\n", formatASSTime(cues[i].Start), formatASSTime(cues[i].End), assText(&cues[i]))
+	}
+	return bw.Flush()
+}
+
+// SubtitleFormat is the file format WriteSubtitleFiles writes
+type SubtitleFormat uint8
+
+const (
+	SubtitleSRT SubtitleFormat = iota
+	SubtitleWebVTT
+	SubtitleASS
+)
+
+// Ext gives the file extension of the format
+func (f SubtitleFormat) Ext() string {
+	switch f {
+	case SubtitleWebVTT:
+		return "vtt"
+	case SubtitleASS:
+		return "ass"
+	}
+	return "srt"
+}
+
+// ParseSubtitleFormat parses a format by its file extension
+func ParseSubtitleFormat(name string) (SubtitleFormat, error) {
+	for _, f := range []SubtitleFormat{SubtitleSRT, SubtitleWebVTT, SubtitleASS} {
+		if f.Ext() == strings.ToLower(strings.TrimPrefix(name, ".")) {
+			return f, nil
+		}
+	}
+	return SubtitleSRT, errors.New("unknown subtitle format: " + name)
+}
+
+// Write writes the cues in the format
+func (f SubtitleFormat) Write(w io.Writer, cues []CaptionCue) error {
+	switch f {
+	case SubtitleWebVTT:
+		return WriteWebVTT(w, cues)
+	case SubtitleASS:
+		return WriteASS(w, cues)
+	}
+	return WriteSRT(w, cues)
+}
+
+// WriteSubtitleFiles writes one file for each language of the cues, named like base.jpn.srt by the language code.
+// The paths written are returned in the order of language tags.
+func (e *CaptionExtractor) WriteSubtitleFiles(cues []CaptionCue, base string, format SubtitleFormat) ([]string, error) {
+	byLanguage := map[uint8][]CaptionCue{}
+	for _, cue := range cues {
+		byLanguage[cue.Language] = append(byLanguage[cue.Language], cue)
+	}
+	var paths []string
+	used := map[string]bool{}
+	for tag := uint8(0); tag < 8; tag++ {
+		languageCues, ok := byLanguage[tag]
+		if !ok {
+			continue
+		}
+		name := e.LanguageCode(tag)
+		if name == "" {
+			name = "lang"
+		}
+		if name == "lang" || used[name] {
+			name = fmt.Sprintf("%s%d", name, tag)
+		}
+		used[name] = true
+		path := fmt.Sprintf("%s.%s.%s", base, name, format.Ext())
+		f, err := os.Create(path)
+		if err != nil {
+			return paths, err
+		}
+		err = format.Write(f, languageCues)
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return paths, err
+		}
+		paths = append(paths, path)
+	}
+	return paths, nil
+}
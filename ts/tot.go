@@ -0,0 +1,30 @@
+package ts
+
+import (
+	"errors"
+	"time"
+)
+
+// TOTFrame is TDT or TOT, giving the current time in JST
+type TOTFrame struct {
+	TableID TableID   `json:"table_id"`
+	JSTTime time.Time `json:"jst_time"`
+}
+
+func (f *TOTFrame) IsParsed() bool {
+	return true
+}
+
+func (f *TOTFrame) GetType() string {
+	if f.TableID == TDTTID {
+		return "TDT"
+	}
+	return "TOT"
+}
+
+func parseTOT(payload []byte, _ *Decoder) (Frame, error) {
+	if len(payload) < 8 || (TableID(payload[0]) != TDTTID && TableID(payload[0]) != TOTTID) {
+		return nil, errors.New("illegal TOT frame")
+	}
+	return &TOTFrame{TableID(payload[0]), parseMjd(payload[3:8])}, nil
+}
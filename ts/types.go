@@ -1,14 +1,20 @@
 package ts
 
+import (
+	"fmt"
+
+	"github.com/zlm2012/wildwrap/b24"
+)
+
 type PATFrame struct {
-	TransportStreamID []byte
-	Version           uint8
-	CurrentNext       bool
-	Section           uint8
-	LastSection       uint8
+	TransportStreamID []byte `json:"transport_stream_id"`
+	Version           uint8  `json:"version"`
+	CurrentNext       bool   `json:"current_next"`
+	Section           uint8  `json:"section"`
+	LastSection       uint8  `json:"last_section"`
 
-	NetworkPID uint16
-	SidPidMap  map[uint16]uint16
+	NetworkPID uint16            `json:"network_pid"`
+	SidPidMap  map[uint16]uint16 `json:"sid_pid_map"`
 }
 
 func (f *PATFrame) IsParsed() bool {
@@ -19,19 +25,86 @@ func (f *PATFrame) GetType() string {
 	return "PAT"
 }
 
+// Descriptor is a descriptor not parsed into fields, kept with its raw content
+type Descriptor struct {
+	Tag  uint8
+	Data []byte
+}
+
+func (d Descriptor) String() string {
+	return fmt.Sprintf("tag 0x%02x: % x", d.Tag, d.Data)
+}
+
+type CADescriptor struct {
+	CASystemID  uint16 `json:"ca_system_id"`
+	CAPID       uint16 `json:"ca_pid"`
+	PrivateData []byte `json:"private_data"`
+}
+
+type ISO639LanguageDescriptor struct {
+	LangCode  string `json:"lang_code"`
+	AudioType uint8  `json:"audio_type"`
+}
+
+type DataComponentDescriptor struct {
+	DataComponentId          uint16 `json:"data_component_id"`
+	AdditionalIdentification []byte `json:"additional_identification"`
+}
+
 type ESInfo struct {
-	StreamId uint8
-	PID      uint16
+	StreamId        StreamType                 `json:"stream_type"`
+	PID             uint16                     `json:"pid"`
+	HasComponentTag bool                       `json:"has_component_tag"`
+	ComponentTag    uint8                      `json:"component_tag"`
+	Languages       []ISO639LanguageDescriptor `json:"languages"`
+	CA              []CADescriptor             `json:"ca"`
+	DataComponent   DataComponentDescriptor    `json:"data_component"`
+	// UnknownDescriptors are the descriptors of the ES not parsed
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
+}
+
+// LangCode returns the first ISO 639 language code announced for the ES, or empty string if none
+func (e *ESInfo) LangCode() string {
+	if len(e.Languages) == 0 {
+		return ""
+	}
+	return e.Languages[0].LangCode
 }
 
 type PMTFrame struct {
-	ServiceID   uint16
-	Version     uint8
-	CurrentNext bool
-	Session     uint8
-	LastSession uint8
-	PcrPID      uint16
-	StreamList  []ESInfo
+	ServiceID   uint16         `json:"service_id"`
+	Version     uint8          `json:"version"`
+	CurrentNext bool           `json:"current_next"`
+	Session     uint8          `json:"section"`
+	LastSession uint8          `json:"last_section"`
+	PcrPID      uint16         `json:"pcr_pid"`
+	CA          []CADescriptor `json:"ca"`
+	StreamList  []ESInfo       `json:"stream_list"`
+	// UnknownDescriptors are the descriptors of the program info not parsed
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
+}
+
+// IsScrambled reports whether any CA descriptor is found in program info or ES info
+func (f *PMTFrame) IsScrambled() bool {
+	if len(f.CA) > 0 {
+		return true
+	}
+	for _, es := range f.StreamList {
+		if len(es.CA) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// FindByComponentTag returns the ES with the given component tag, or nil if not found
+func (f *PMTFrame) FindByComponentTag(tag uint8) *ESInfo {
+	for i := range f.StreamList {
+		if f.StreamList[i].HasComponentTag && f.StreamList[i].ComponentTag == tag {
+			return &f.StreamList[i]
+		}
+	}
+	return nil
 }
 
 func (f *PMTFrame) IsParsed() bool {
@@ -43,26 +116,87 @@ func (f *PMTFrame) GetType() string {
 }
 
 type TSInfo struct {
-	RemoteControlKeyId uint8
-	TSName             string
+	RemoteControlKeyId uint8  `json:"remote_control_key_id"`
+	TSName             string `json:"ts_name"`
+}
+
+// SatelliteDeliverySystemDescriptor holds the BCD-coded fields already converted to integers
+type SatelliteDeliverySystemDescriptor struct {
+	Frequency       uint32       `json:"frequency"`        // in 10kHz
+	OrbitalPosition uint16       `json:"orbital_position"` // in 0.1 degree
+	East            bool         `json:"east"`
+	Polarization    Polarization `json:"polarization"`
+	Modulation      uint8        `json:"modulation"`
+	SymbolRate      uint32       `json:"symbol_rate"` // in 100 symbol/s
+	FECInner        uint8        `json:"fec_inner"`
+}
+
+func (d *SatelliteDeliverySystemDescriptor) FrequencyMHz() float64 {
+	return float64(d.Frequency) / 100
+}
+
+func (d *SatelliteDeliverySystemDescriptor) OrbitalPositionDegree() float64 {
+	return float64(d.OrbitalPosition) / 10
+}
+
+type TerrestrialDeliverySystemDescriptor struct {
+	AreaCode         uint16   `json:"area_code"`
+	GuardInterval    uint8    `json:"guard_interval"`
+	TransmissionMode uint8    `json:"transmission_mode"`
+	Frequencies      []uint16 `json:"frequencies"` // in 1/7 MHz
+}
+
+func (d *TerrestrialDeliverySystemDescriptor) FrequenciesMHz() []float64 {
+	result := make([]float64, len(d.Frequencies))
+	for i, f := range d.Frequencies {
+		result[i] = float64(f) / 7
+	}
+	return result
+}
+
+// UHFChannel returns the UHF physical channel of the first frequency, or 0 if none is announced
+func (d *TerrestrialDeliverySystemDescriptor) UHFChannel() int {
+	if len(d.Frequencies) == 0 {
+		return 0
+	}
+	// ch13 is centered on 473+1/7 MHz, 6MHz per channel
+	return (int(d.Frequencies[0])-3312)/42 + 13
+}
+
+type SystemManagementDescriptor struct {
+	BroadcastingFlag         uint8  `json:"broadcasting_flag"`
+	BroadcastingIdentifier   uint8  `json:"broadcasting_identifier"`
+	AdditionalBroadcastingID uint8  `json:"additional_broadcasting_id"`
+	AdditionalIdentification []byte `json:"additional_identification"`
 }
 
 type NITTransportEntry struct {
-	TransportStreamId uint16
-	OriginalNetworkId uint16
-	NetworkName       string
-	ServiceList       map[uint16]ServiceType
-	TSInfo            TSInfo
+	TransportStreamId        uint16                               `json:"transport_stream_id"`
+	OriginalNetworkId        uint16                               `json:"original_network_id"`
+	NetworkName              string                               `json:"network_name"`
+	ServiceList              map[uint16]ServiceType               `json:"service_list"`
+	TSInfo                   TSInfo                               `json:"ts_info"`
+	Satellite                *SatelliteDeliverySystemDescriptor   `json:"satellite"`
+	Terrestrial              *TerrestrialDeliverySystemDescriptor `json:"terrestrial"`
+	PartialReceptionServices []uint16                             `json:"partial_reception_services"`
+	SystemManagement         []SystemManagementDescriptor         `json:"system_management"`
+	DataComponents           []DataComponentDescriptor            `json:"data_components"`
+	UnknownDescriptors       []Descriptor                         `json:"unknown_descriptors"`
 }
 
 type NITFrame struct {
-	NetworkID        uint16
-	Version          uint8
-	CurrentNext      bool
-	Section          uint8
-	LastSection      uint8
-	NetworkName      string
-	TransportStreams []NITTransportEntry
+	TableID          TableID                      `json:"table_id"`
+	NetworkID        uint16                       `json:"network_id"`
+	Version          uint8                        `json:"version"`
+	CurrentNext      bool                         `json:"current_next"`
+	Section          uint8                        `json:"section"`
+	LastSection      uint8                        `json:"last_section"`
+	NetworkName      string                       `json:"network_name"`
+	SystemManagement []SystemManagementDescriptor `json:"system_management"`
+	TransportStreams []NITTransportEntry          `json:"transport_streams"`
+	// UnknownDescriptors are the network descriptors not parsed
+	UnknownDescriptors []Descriptor  `json:"unknown_descriptors"`
+	TextWarnings       []b24.Warning `json:"text_warnings"`
 }
 
 func (f *NITFrame) IsParsed() bool {
@@ -74,30 +208,34 @@ func (f *NITFrame) GetType() string {
 }
 
 type LogoTransmissionDescriptor struct {
-	LogoTransmissionType uint8
-	LogoId               uint16
-	LogoVersion          uint16
-	DownloadDataId       uint16
-	LogoStr              string
+	LogoTransmissionType uint8  `json:"logo_transmission_type"`
+	LogoId               uint16 `json:"logo_id"`
+	LogoVersion          uint16 `json:"logo_version"`
+	DownloadDataId       uint16 `json:"download_data_id"`
+	LogoStr              string `json:"logo_str"`
 }
 
 type SDTFrameEntry struct {
-	ServiceID    uint16
-	EITFlags     uint8
-	RunningState SDTRunningState
-	Scramble     bool
-	Service      ServiceDescriptor
-	Logo         LogoTransmissionDescriptor
+	ServiceID    uint16                     `json:"service_id"`
+	EITFlags     uint8                      `json:"eit_flags"`
+	RunningState SDTRunningState            `json:"running_state"`
+	Scramble     bool                       `json:"scramble"`
+	Service      ServiceDescriptor          `json:"service"`
+	Logo         LogoTransmissionDescriptor `json:"logo"`
+	// UnknownDescriptors are the descriptors of the service not parsed
+	UnknownDescriptors []Descriptor `json:"unknown_descriptors"`
 }
 
 type SDTFrame struct {
-	TransportStreamID uint16
-	Version           uint8
-	CurrentNext       bool
-	Section           uint8
-	LastSection       uint8
-	OriginalNetworkID uint16
-	Entries           []SDTFrameEntry
+	TableID           TableID         `json:"table_id"`
+	TransportStreamID uint16          `json:"transport_stream_id"`
+	Version           uint8           `json:"version"`
+	CurrentNext       bool            `json:"current_next"`
+	Section           uint8           `json:"section"`
+	LastSection       uint8           `json:"last_section"`
+	OriginalNetworkID uint16          `json:"original_network_id"`
+	Entries           []SDTFrameEntry `json:"entries"`
+	TextWarnings      []b24.Warning   `json:"text_warnings"`
 }
 
 func (f *SDTFrame) IsParsed() bool {
@@ -109,12 +247,104 @@ func (f *SDTFrame) GetType() string {
 }
 
 type ServiceDescriptor struct {
-	ServiceType         ServiceType
-	ServiceProviderName string
-	ServiceName         string
+	ServiceType         ServiceType `json:"service_type"`
+	ServiceProviderName string      `json:"service_provider_name"`
+	ServiceName         string      `json:"service_name"`
 }
 
 type Genre uint8
 type SubGenre uint8
 type SDTRunningState uint8
 type ServiceType uint8
+type Polarization uint8
+type TableID uint8
+type StreamType uint8
+
+func (s SDTRunningState) String() string {
+	switch s {
+	case RSUndefined:
+		return "undefined"
+	case RSNotRunning:
+		return "not running"
+	case RSStartSoon:
+		return "starts soon"
+	case RSStopped:
+		return "stopped"
+	case RSRunning:
+		return "running"
+	}
+	return fmt.Sprintf("0x%x", uint8(s))
+}
+
+func (p Polarization) String() string {
+	switch p {
+	case PolarizationLinearHorizontal:
+		return "H"
+	case PolarizationLinearVertical:
+		return "V"
+	case PolarizationCircularLeft:
+		return "L"
+	case PolarizationCircularRight:
+		return "R"
+	default:
+		return "unknown"
+	}
+}
+
+func (t TableID) String() string {
+	switch {
+	case t == PATTID:
+		return "PAT"
+	case t == PMTTID:
+		return "PMT"
+	case t == NITActualTID:
+		return "NIT actual"
+	case t == NITOtherTID:
+		return "NIT other"
+	case t == SDTActualTID:
+		return "SDT actual"
+	case t == SDTOtherTID:
+		return "SDT other"
+	case t == EITCurrentStreamTID:
+		return "EIT p/f actual"
+	case t == EITOtherStreamTID:
+		return "EIT p/f other"
+	case t&0xf0 == EITCurrentSchedTIDMask:
+		return fmt.Sprintf("EIT schedule actual %d", t&0x0f)
+	case t&0xf0 == EITOtherSchedTIDMask:
+		return fmt.Sprintf("EIT schedule other %d", t&0x0f)
+	case t == TDTTID:
+		return "TDT"
+	case t == TOTTID:
+		return "TOT"
+	case t == BITTID:
+		return "BIT"
+	case t == CDTTID:
+		return "CDT"
+	}
+	return "unknown"
+}
+
+func (t StreamType) String() string {
+	switch t {
+	case StreamTypeMPEG1Video:
+		return "MPEG-1 Video"
+	case StreamTypeMPEG2Video:
+		return "MPEG-2 Video"
+	case StreamTypePESPrivate:
+		return "PES private data"
+	case StreamTypeDSMCCTypeB:
+		return "DSM-CC type B"
+	case StreamTypeDSMCCTypeD:
+		return "DSM-CC type D"
+	case StreamTypeAACADTS:
+		return "AAC ADTS"
+	case StreamTypeAACLATM:
+		return "AAC LATM"
+	case StreamTypeH264:
+		return "H.264"
+	case StreamTypeH265:
+		return "H.265"
+	}
+	return "unknown"
+}
@@ -0,0 +1,214 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// websocketGUID is the key suffix of RFC 6455 for Sec-WebSocket-Accept
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+const (
+	// wsClientQueue is the number of messages waiting to be sent to a client, which is dropped when it falls behind more
+	wsClientQueue = 64
+	// wsWriteTimeout bounds writing a frame to a client, so that a stalled connection is dropped
+	wsWriteTimeout = 10 * time.Second
+)
+
+const (
+	wsOpText  = 0x1
+	wsOpClose = 0x8
+	wsOpPing  = 0x9
+	wsOpPong  = 0xA
+)
+
+// wsConn is a server side WebSocket connection, which only sends text messages
+type wsConn struct {
+	conn net.Conn
+	rw   *bufio.ReadWriter
+	mu   sync.Mutex
+}
+
+// upgradeWebSocket does the opening handshake of RFC 6455
+func upgradeWebSocket(w http.ResponseWriter, r *http.Request) (*wsConn, error) {
+	if !strings.EqualFold(r.Header.Get("Upgrade"), "websocket") || r.Header.Get("Sec-WebSocket-Key") == "" {
+		http.Error(w, "websocket is expected", http.StatusBadRequest)
+		return nil, errors.New("not a websocket request")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket is not supported", http.StatusInternalServerError)
+		return nil, errors.New("response writer cannot be hijacked")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+	sum := sha1.Sum([]byte(r.Header.Get("Sec-WebSocket-Key") + websocketGUID))
+	rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n")
+	rw.WriteString("Sec-WebSocket-Accept: " + base64.StdEncoding.EncodeToString(sum[:]) + "\r\n\r\n")
+	if err := rw.Flush(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &wsConn{conn: conn, rw: rw}, nil
+}
+
+func (c *wsConn) writeFrame(opcode byte, payload []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.conn.SetWriteDeadline(time.Now().Add(wsWriteTimeout))
+	header := []byte{0x80 | opcode}
+	switch {
+	case len(payload) < 126:
+		header = append(header, byte(len(payload)))
+	case len(payload) <= 0xFFFF:
+		header = append(header, 126, byte(len(payload)>>8), byte(len(payload)))
+	default:
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(len(payload)))
+		header = append(append(header, 127), ext[:]...)
+	}
+	c.rw.Write(header)
+	c.rw.Write(payload)
+	return c.rw.Flush()
+}
+
+// WriteText sends a text message
+func (c *wsConn) WriteText(message []byte) error {
+	return c.writeFrame(wsOpText, message)
+}
+
+// readLoop reads frames from the client until it closes, answering ping and close
+func (c *wsConn) readLoop() {
+	defer c.conn.Close()
+	for {
+		opcode, payload, err := c.readFrame()
+		if err != nil {
+			return
+		}
+		switch opcode {
+		case wsOpPing:
+			if c.writeFrame(wsOpPong, payload) != nil {
+				return
+			}
+		case wsOpClose:
+			c.writeFrame(wsOpClose, payload)
+			return
+		}
+	}
+}
+
+func (c *wsConn) readFrame() (byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.rw, head[:]); err != nil {
+		return 0, nil, err
+	}
+	length := uint64(head[1] & 0x7F)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.rw, ext[:]); err != nil {
+			return 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > 1<<20 {
+		return 0, nil, errors.New("websocket frame too large")
+	}
+	var mask [4]byte
+	masked := head[1]&0x80 != 0
+	if masked {
+		if _, err := io.ReadFull(c.rw, mask[:]); err != nil {
+			return 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.rw, payload); err != nil {
+		return 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return head[0] & 0x0F, payload, nil
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+// writeLoop sends the messages of the queue until it is closed, and closes the connection on failure
+func (c *wsConn) writeLoop(queue <-chan []byte) {
+	for message := range queue {
+		if err := c.WriteText(message); err != nil {
+			c.Close()
+			return
+		}
+	}
+}
+
+// wsHub broadcasts messages to all the connected clients, each of which has its own queue
+// so that a slow client does not hold the others and the broadcaster
+type wsHub struct {
+	mu      sync.Mutex
+	clients map[*wsConn]chan []byte
+}
+
+func newWSHub() *wsHub {
+	return &wsHub{clients: map[*wsConn]chan []byte{}}
+}
+
+func (h *wsHub) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgradeWebSocket(w, r)
+	if err != nil {
+		return
+	}
+	queue := make(chan []byte, wsClientQueue)
+	h.mu.Lock()
+	h.clients[conn] = queue
+	h.mu.Unlock()
+	go conn.writeLoop(queue)
+	conn.readLoop()
+	h.mu.Lock()
+	h.drop(conn)
+	h.mu.Unlock()
+}
+
+// drop closes the connection and its queue if it is still kept, h.mu must be held
+func (h *wsHub) drop(conn *wsConn) {
+	if queue, ok := h.clients[conn]; ok {
+		conn.Close()
+		close(queue)
+		delete(h.clients, conn)
+	}
+}
+
+// Broadcast queues the message to every client without waiting, dropping those whose queue is full
+func (h *wsHub) Broadcast(message []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for conn, queue := range h.clients {
+		select {
+		case queue <- message:
+		default:
+			h.drop(conn)
+		}
+	}
+}
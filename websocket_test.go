@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitClients waits until the hub has n clients
+func waitClients(t *testing.T, hub *wsHub, n int) {
+	t.Helper()
+	for i := 0; i < 100; i++ {
+		hub.mu.Lock()
+		count := len(hub.clients)
+		hub.mu.Unlock()
+		if count == n {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatalf("hub does not have %d clients", n)
+}
+
+func TestWSHubBroadcast(t *testing.T) {
+	hub := newWSHub()
+	server := httptest.NewServer(hub)
+	defer server.Close()
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	conn.Write([]byte("GET / HTTP/1.1\r\nHost: localhost\r\nUpgrade: websocket\r\nConnection: Upgrade\r\n" +
+		"Sec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n"))
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatalf("unexpected handshake: %s %v", resp.Status, resp.Header)
+	}
+	waitClients(t, hub, 1)
+
+	hub.Broadcast([]byte("hello"))
+	frame := make([]byte, 7)
+	if _, err := io.ReadFull(reader, frame); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(frame, []byte{0x81, 5, 'h', 'e', 'l', 'l', 'o'}) {
+		t.Fatalf("unexpected frame: %02x", frame)
+	}
+
+	// close from the client, masked
+	conn.Write([]byte{0x88, 0x80, 1, 2, 3, 4})
+	waitClients(t, hub, 0)
+}
+
+func TestWSHubDropsSlowClient(t *testing.T) {
+	hub := newWSHub()
+	server, client := net.Pipe()
+	defer client.Close()
+	// the client never reads, so the first write blocks
+	conn := &wsConn{conn: server, rw: bufio.NewReadWriter(bufio.NewReader(server), bufio.NewWriter(server))}
+	queue := make(chan []byte, wsClientQueue)
+	hub.clients[conn] = queue
+	go conn.writeLoop(queue)
+
+	done := make(chan bool)
+	go func() {
+		for i := 0; i < wsClientQueue+2; i++ {
+			hub.Broadcast([]byte("message"))
+		}
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("broadcast is blocked by the slow client")
+	}
+	waitClients(t, hub, 0)
+}
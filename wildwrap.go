@@ -1,25 +1,58 @@
 package main
 
 import (
+	"errors"
+	"fmt"
 	"github.com/zlm2012/wildwrap/ts"
+	"io"
 	"log"
 	"os"
 )
 
 func main() {
-	file, err := os.Open(os.Args[1])
+	if len(os.Args) < 2 {
+		fmt.Fprintf(os.Stderr, "usage: %s [live|index|search|probe|dump] <args>\n", os.Args[0])
+		os.Exit(2)
+	}
+	switch os.Args[1] {
+	case "live":
+		runLive(os.Args[2:])
+	case "index":
+		runIndex(os.Args[2:])
+	case "search":
+		runSearch(os.Args[2:])
+	case "probe":
+		runProbe(os.Args[2:])
+	case "dump":
+		runDump(os.Args[2:])
+	default:
+		printEIT(os.Args[1])
+	}
+}
+
+// printEIT logs the first present/following EIT frames of the actual stream in the file
+func printEIT(path string) {
+	file, err := os.Open(path)
 	if err != nil {
 		log.Fatalln(err)
 	}
 	decoder := ts.NewDecoder(file)
 	eitSucceededCount := 0
 	for {
-		log.Println("try get next eit")
-		eitFrame, err := decoder.ReadNextCurrentStreamEITFrame()
-		if err != nil {
+		frame, err := decoder.ParseNext()
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return
+		}
+		var readErr *ts.ReadError
+		if errors.As(err, &readErr) {
 			log.Fatalln(err)
 		}
-		if eitFrame != nil {
+		if err != nil {
+			log.Println(err)
+			continue
+		}
+		// present and following event of the actual stream
+		if eitFrame, ok := frame.(*ts.EITFrame); ok && eitFrame.TableID == ts.EITCurrentStreamTID {
 			log.Println(*eitFrame)
 			eitSucceededCount++
 			if eitSucceededCount > 1 {